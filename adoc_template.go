@@ -0,0 +1,89 @@
+package godoc2md
+
+// adocTemplate is the built-in template of the `adoc` format.
+var adocTemplate = `{{with .PDoc -}}
+{{- if $.IsMain -}}
+= {{base .ImportPath}}
+
+{{comment .Doc}}
+{{- else -}}
+= {{.Name}}
+
+` + "`" + `import "{{.ImportPath}}"` + "`" + `
+
+* <<pkg-overview,Overview>>
+* <<pkg-index,Index>>
+
+[[pkg-overview]]
+== Overview
+
+{{doc .Doc}}[[pkg-index]]
+== Index
+
+{{if .Consts}}* <<pkg-constants,Constants>>
+{{end}}{{if .Vars}}* <<pkg-variables,Variables>>
+{{end}}{{range .Funcs}}* <<{{symbol_anchor "" .Name}},{{node $ .Decl | escape | strike_deprecated .Doc}}>>
+{{end}}{{range .Types}}{{$tname := .Name}}* <<{{symbol_anchor "" $tname}},{{printf "type %s" $tname | strike_deprecated .Doc}}>>
+{{if is_enum .}}** <<{{symbol_anchor $tname "values"}},Values>>
+{{end}}{{range .Funcs}}** <<{{symbol_anchor "" .Name}},{{node $ .Decl | escape | strike_deprecated .Doc}}>>
+{{end}}{{range .Methods}}** <<{{symbol_anchor $tname .Name}},{{node $ .Decl | escape | strike_deprecated .Doc}}>>
+{{end}}{{end}}{{range $marker, $item := $.Notes}}* <<pkg-note-{{$marker}},{{noteTitle $marker}}s>>
+{{end}}
+{{with .Filenames}}[[pkg-files]]
+=== Package files
+
+{{range .}}{{srcfile_url .}}[{{filename .}}] {{end}}
+
+{{end}}
+{{- with .Consts}}[[pkg-constants]]
+== Constants
+
+{{range .}}{{node $ .Decl | go_block}}{{platforms $ .}}{{doc .Doc}}{{end}}
+{{- end}}
+{{- with .Vars}}[[pkg-variables]]
+== Variables
+
+{{range .}}{{node $ .Decl | go_block}}{{platforms $ .}}{{doc .Doc}}{{end}}
+{{- end}}
+{{- range .Funcs}}[[{{symbol_anchor "" .Name}}]]
+== func {{get_full_url $ .Decl}}[{{.Name}}]
+
+{{node $ .Decl | go_block}}{{platforms $ .}}{{doc .Doc}}
+{{- end}}
+{{- range .Types}}{{$tname := .Name}}[[{{symbol_anchor "" $tname}}]]
+== type {{get_full_url $ .Decl}}[{{$tname}}]
+
+{{node $ .Decl | go_block}}{{platforms $ .}}{{doc .Doc}}
+{{- if is_enum .}}[[{{symbol_anchor $tname "values"}}]]
+=== Values
+
+{{range .Consts}}{{node $ .Decl | go_block}}{{platforms $ .}}{{doc .Doc}}{{end}}
+{{- if has_stringer .}}` + "`" + `{{$tname}}` + "`" + ` values print by name through <<{{symbol_anchor $tname "String"}},String>>.
+
+{{end}}
+{{- else}}{{range .Consts}}{{node $ .Decl | go_block}}{{platforms $ .}}{{doc .Doc}}{{end}}
+{{- end}}
+{{- range .Vars}}{{node $ .Decl | go_block}}{{platforms $ .}}{{doc .Doc}}{{end}}
+{{- range .Funcs}}[[{{symbol_anchor "" .Name}}]]
+=== func {{get_full_url $ .Decl}}[{{.Name}}]
+
+{{node $ .Decl | go_block}}{{platforms $ .}}{{doc .Doc}}
+{{- end}}
+{{- range .Methods}}[[{{symbol_anchor $tname .Name}}]]
+=== func ({{escape .Recv}}) {{get_full_url $ .Decl}}[{{.Name}}]
+
+{{node $ .Decl | go_block}}{{platforms $ .}}{{doc .Doc}}
+{{- end}}
+{{- end}}
+{{- range $marker, $content := $.Notes}}[[pkg-note-{{$marker}}]]
+== {{noteTitle $marker}}s
+
+{{range .}}* {{with .UID}}*{{.}}* {{end}}({{note_url $ .}}[source]): {{oneline .Body | escape}}
+{{end}}
+{{end}}
+{{- end}}
+'''''
+Created: {{current_time}} +
+Generated by https://github.com/chriswgerber/godoc2md[godoc2md]
+{{end -}}
+`
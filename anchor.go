@@ -0,0 +1,191 @@
+package godoc2md
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/tools/godoc"
+)
+
+// Forge is a source code hosting service. Each forge derives the ids of
+// Markdown headings from their text with its own slug algorithm.
+type Forge string
+
+// The forges whose heading slugs are supported.
+const (
+	ForgeGitHub    Forge = "github"
+	ForgeGitLab    Forge = "gitlab"
+	ForgeBitbucket Forge = "bitbucket"
+)
+
+var forges = []Forge{ForgeBitbucket, ForgeGitHub, ForgeGitLab}
+
+// GetForge returns the forge named name.
+func GetForge(name string) (Forge, error) {
+	for _, forge := range forges {
+		if string(forge) == name {
+			return forge, nil
+		}
+	}
+
+	names := make([]string, len(forges))
+	for i, forge := range forges {
+		names[i] = string(forge)
+	}
+	return "", fmt.Errorf("unknown forge %q, must be one of: %s", name, strings.Join(names, ", "))
+}
+
+// Slug returns the id the forge generates for a heading with the text
+// heading, before de-duplication.
+func (f Forge) Slug(heading string) string {
+	if f == ForgeBitbucket {
+		return "markdown-header-" + f.slug(heading)
+	}
+	return f.slug(heading)
+}
+
+// slug returns the slug of heading, without the prefix of the forge.
+func (f Forge) slug(heading string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(strings.TrimSpace(heading)) {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), unicode.IsMark(r), r == '_':
+			hyphen = false
+		case r == '-', r == ' ':
+			// GitLab collapses runs of hyphens, GitHub and Bitbucket keep
+			// one per space or hyphen.
+			if f == ForgeGitLab && hyphen {
+				continue
+			}
+			hyphen = true
+			r = '-'
+		default:
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// suffix returns the slug of the nth duplicate of a heading with the slug
+// slug, counting from 1.
+func (f Forge) suffix(slug string, n int) string {
+	if f == ForgeBitbucket {
+		return slug + "_" + strconv.Itoa(n)
+	}
+	return slug + "-" + strconv.Itoa(n)
+}
+
+// Anchors hands out the anchor ids of a single page, guaranteeing that no id
+// is used twice. Repeated headings are told apart with the suffix of the
+// forge, as in "usage", "usage-1", "usage-2".
+type Anchors struct {
+	forge Forge
+	seen  map[string]bool
+}
+
+// NewAnchors returns an empty set of anchors, slugged for forge.
+func NewAnchors(forge Forge) *Anchors {
+	return &Anchors{forge: forge, seen: map[string]bool{}}
+}
+
+// Reserve marks id as used, so that no heading is given the same id.
+func (a *Anchors) Reserve(id string) {
+	a.seen[id] = true
+}
+
+// Heading returns a new, unique, id for a heading with the text heading.
+func (a *Anchors) Heading(heading string) string {
+	return a.unique(a.forge.Slug(heading))
+}
+
+// prefixed returns a new, unique, id made of prefix and the slug of text,
+// for anchors that are not derived from a heading by the forge.
+func (a *Anchors) prefixed(prefix, text string) string {
+	return a.unique(prefix + a.forge.slug(text))
+}
+
+// unique returns slug, suffixed as the forge does if it is already used, and
+// marks it as used.
+func (a *Anchors) unique(slug string) string {
+	id := slug
+	for n := 1; a.seen[id]; n++ {
+		id = a.forge.suffix(slug, n)
+	}
+	a.Reserve(id)
+
+	return id
+}
+
+// ReservePage reserves the ids the built-in templates give the sections and
+// symbols of the package documented by info, along with the ids the forge
+// derives from the text of their headings, so that doc headings never shadow
+// them.
+func (a *Anchors) ReservePage(info *godoc.PageInfo) {
+	for _, id := range []string{
+		"pkg-overview", "pkg-index", "pkg-examples", "pkg-subdirectories",
+		"pkg-files", "pkg-constants", "pkg-variables",
+	} {
+		a.Reserve(id)
+	}
+	for _, heading := range []string{
+		"Overview", "Index", "Examples", "Subdirectories", "Package files",
+		"Constants", "Variables", "Functions",
+	} {
+		a.reserveHeading(heading)
+	}
+
+	for marker := range info.Notes {
+		a.Reserve("pkg-note-" + marker)
+		a.reserveHeading(marker + "s")
+	}
+
+	for _, eg := range info.Examples {
+		a.Reserve("example_" + eg.Name)
+	}
+
+	if info.PDoc == nil {
+		return
+	}
+	a.reserveHeading(info.PDoc.Name)
+	for _, f := range info.PDoc.Funcs {
+		a.Reserve(SymbolAnchor("", f.Name))
+		a.reserveHeading("func " + f.Name)
+	}
+	for _, t := range info.PDoc.Types {
+		a.Reserve(SymbolAnchor("", t.Name))
+		a.Reserve(SymbolAnchor(t.Name, "values"))
+		a.reserveHeading("type " + t.Name)
+		a.reserveHeading("Values")
+		for _, f := range t.Funcs {
+			a.Reserve(SymbolAnchor("", f.Name))
+			a.reserveHeading("func " + f.Name)
+		}
+		for _, m := range t.Methods {
+			a.Reserve(SymbolAnchor(t.Name, m.Name))
+			a.reserveHeading("func (" + m.Recv + ") " + m.Name)
+		}
+	}
+}
+
+// reserveHeading reserves the id the forge gives the next heading with the
+// text heading. Headings repeated in the page are reserved once each.
+func (a *Anchors) reserveHeading(heading string) {
+	if heading != "" {
+		a.Heading(heading)
+	}
+}
+
+// SymbolAnchor returns the anchor id of the package level symbol name, or of
+// the method name of the type typeName. Go identifiers are unique within
+// their scope, so symbol anchors need no de-duplication.
+func SymbolAnchor(typeName, name string) string {
+	if typeName == "" {
+		return name
+	}
+	return typeName + "." + name
+}
@@ -0,0 +1,117 @@
+package godoc2md
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestForgeSlug(t *testing.T) {
+	for _, tt := range []struct {
+		heading                   string
+		github, gitlab, bitbucket string
+	}{
+		{"Usage", "usage", "usage", "markdown-header-usage"},
+		{"  Getting Started  ", "getting-started", "getting-started", "markdown-header-getting-started"},
+		{"Hello, World!", "hello-world", "hello-world", "markdown-header-hello-world"},
+		{"func (t *T) String", "func-t-t-string", "func-t-t-string", "markdown-header-func-t-t-string"},
+		{"a - b", "a---b", "a-b", "markdown-header-a---b"},
+		{"foo--bar", "foo--bar", "foo-bar", "markdown-header-foo--bar"},
+		{"snake_case.go", "snake_casego", "snake_casego", "markdown-header-snake_casego"},
+		{"Ünïcödé Straße", "ünïcödé-straße", "ünïcödé-straße", "markdown-header-ünïcödé-straße"},
+		{"日本語 テキスト", "日本語-テキスト", "日本語-テキスト", "markdown-header-日本語-テキスト"},
+		{"Version 2.0 🚀", "version-20-", "version-20-", "markdown-header-version-20-"},
+		{"", "", "", "markdown-header-"},
+	} {
+		for forge, want := range map[Forge]string{
+			ForgeGitHub:    tt.github,
+			ForgeGitLab:    tt.gitlab,
+			ForgeBitbucket: tt.bitbucket,
+		} {
+			if got := forge.Slug(tt.heading); got != want {
+				t.Errorf("%s: Slug(%q) = %q, want %q", forge, tt.heading, got, want)
+			}
+		}
+	}
+}
+
+func TestAnchorsHeading(t *testing.T) {
+	for _, tt := range []struct {
+		forge    Forge
+		reserved []string
+		headings []string
+		want     []string
+	}{
+		{
+			forge:    ForgeGitHub,
+			headings: []string{"Usage", "Usage", "usage!", "Usage"},
+			want:     []string{"usage", "usage-1", "usage-2", "usage-3"},
+		},
+		{
+			forge:    ForgeGitLab,
+			headings: []string{"Usage", "Usage", "a - b", "a-b"},
+			want:     []string{"usage", "usage-1", "a-b", "a-b-1"},
+		},
+		{
+			forge:    ForgeBitbucket,
+			headings: []string{"Usage", "Usage", "Usage"},
+			want:     []string{"markdown-header-usage", "markdown-header-usage_1", "markdown-header-usage_2"},
+		},
+		{
+			// a heading whose slug is a suffixed duplicate takes the
+			// next free suffix
+			forge:    ForgeGitHub,
+			headings: []string{"Usage", "Usage 1", "Usage", "Usage"},
+			want:     []string{"usage", "usage-1", "usage-2", "usage-3"},
+		},
+		{
+			forge:    ForgeGitHub,
+			headings: []string{"Usage", "Usage 1", "Usage 1"},
+			want:     []string{"usage", "usage-1", "usage-1-1"},
+		},
+		{
+			forge:    ForgeGitHub,
+			reserved: []string{"pkg-index", "index", "index-1"},
+			headings: []string{"Index", "pkg index"},
+			want:     []string{"index-2", "pkg-index-1"},
+		},
+		{
+			forge:    ForgeBitbucket,
+			reserved: []string{"markdown-header-overview"},
+			headings: []string{"Overview"},
+			want:     []string{"markdown-header-overview_1"},
+		},
+	} {
+		anchors := NewAnchors(tt.forge)
+		for _, id := range tt.reserved {
+			anchors.Reserve(id)
+		}
+		var got []string
+		for _, heading := range tt.headings {
+			got = append(got, anchors.Heading(heading))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Heading(%q) = %q, want %q", tt.forge, tt.headings, got, tt.want)
+		}
+	}
+}
+
+func TestAnchorsPrefixed(t *testing.T) {
+	for _, tt := range []struct {
+		forge Forge
+		texts []string
+		want  []string
+	}{
+		{ForgeGitHub, []string{"a.b.go", "ab.go", "foo_bar.go"}, []string{"pkg-file-abgo", "pkg-file-abgo-1", "pkg-file-foo_bargo"}},
+		{ForgeGitLab, []string{"a.b.go", "ab.go"}, []string{"pkg-file-abgo", "pkg-file-abgo-1"}},
+		{ForgeBitbucket, []string{"a.b.go", "ab.go"}, []string{"pkg-file-abgo", "pkg-file-abgo_1"}},
+	} {
+		anchors := NewAnchors(tt.forge)
+		var got []string
+		for _, text := range tt.texts {
+			got = append(got, anchors.prefixed("pkg-file-", text))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: prefixed(%q) = %q, want %q", tt.forge, tt.texts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,425 @@
+package godoc2md
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/scanner"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/godoc"
+	"golang.org/x/tools/godoc/vfs"
+)
+
+// DiffCmd is the name of the subcommand writing the changelog of the exported
+// API of a package between two revisions, as in
+// `godoc2md diff <old> <new> <package>`.
+const DiffCmd = "diff"
+
+// The kinds of API changes.
+const (
+	APIAdded   = "added"
+	APIRemoved = "removed"
+	APIChanged = "changed"
+)
+
+// APIChange is a change to a single exported symbol of a package.
+type APIChange struct {
+	// Kind is one of APIAdded, APIRemoved or APIChanged.
+	Kind string
+	// Symbol is the name of the symbol, with fields and methods qualified
+	// by their type, as in "Type.Method".
+	Symbol string
+	// Old and New are the declarations of the symbol before and after the
+	// change, empty if it didn't exist.
+	Old, New string
+	// Breaking is set if code using the old API may no longer compile:
+	// for removed symbols, changed types and signatures, and methods added
+	// to existing interfaces, but not for changed constant values or
+	// struct tags.
+	Breaking bool
+}
+
+// apiSymbol is an exported symbol, as compared between revisions.
+type apiSymbol struct {
+	// decl is the declaration of the symbol, as reported.
+	decl string
+	// sig is the part of the declaration code using the symbol depends on,
+	// with the names of parameters and results dropped. Changes to it are
+	// breaking.
+	sig string
+	// value is the value of a constant, or the tag of a struct field,
+	// whose changes are not breaking.
+	value string
+	// iface is the name of the interface type of an interface method,
+	// which can't be added to an existing interface without breaking its
+	// implementations.
+	iface string
+}
+
+// DiffCommand writes the Markdown changelog of the exported API of the
+// package at args[2] between the revisions args[0] and args[1] to w. A
+// revision is either a directory, which the package path is relative to, or a
+// git revision of the repository containing the package directory.
+func DiffCommand(w io.Writer, fs vfs.NameSpace, pres *godoc.Presentation, cfg *Cli, args []string) error {
+	if len(args) != 3 {
+		return fmt.Errorf("usage: %s %s <old> <new> <package>", cmdName, DiffCmd)
+	}
+	oldRev, newRev, pkgPath := args[0], args[1], args[2]
+
+	// Unexported declarations are kept, so that constants know the type,
+	// value and iota of the specs they repeat.
+	fs, pres = workerPresentation(fs, pres, cfg)
+	pres.AllMode = true
+
+	oldAPI, err := loadRevisionAPI(fs, pres, oldRev, pkgPath)
+	if err != nil {
+		return err
+	}
+	newAPI, err := loadRevisionAPI(fs, pres, newRev, pkgPath)
+	if err != nil {
+		return err
+	}
+
+	writeChangelog(w, pkgPath, oldRev, newRev, DiffAPI(oldAPI, newAPI))
+	return nil
+}
+
+// loadRevisionAPI loads the exported API of the package at pkgPath, as of the
+// revision rev.
+func loadRevisionAPI(fs vfs.NameSpace, pres *godoc.Presentation, rev, pkgPath string) (map[string]apiSymbol, error) {
+	var info *godoc.PageInfo
+	if fi, err := os.Stat(rev); err == nil && fi.IsDir() {
+		dir, err := filepath.Abs(filepath.Join(rev, pkgPath))
+		if err != nil {
+			return nil, err
+		}
+		if info, err = GetPageInfo(fs, pres, []string{dir}); err != nil {
+			return nil, fmt.Errorf("%s: %v", rev, err)
+		}
+	} else {
+		dir, err := filepath.Abs(pkgPath)
+		if err != nil {
+			return nil, err
+		}
+		r, err := OpenRevision(findRepoRoot(dir), rev)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.bind(fs, dir); err != nil {
+			return nil, err
+		}
+		if info, err = getTargetPageInfo(pres, []string{pkgPath}); err != nil {
+			return nil, fmt.Errorf("%s: %v", rev, err)
+		}
+	}
+	if info.PDoc == nil {
+		return nil, fmt.Errorf("%s: %s: no package documentation", rev, pkgPath)
+	}
+
+	node := pres.FuncMap()["node"].(func(*godoc.PageInfo, interface{}) string)
+	return extractAPI(info, func(n interface{}) string { return node(info, n) }), nil
+}
+
+// extractAPI returns the exported symbols of the package documented by info,
+// keyed by name, with their declarations printed by node. Struct types are
+// broken down into their fields, and interface types into their methods, so
+// that changes to them are reported one by one.
+func extractAPI(info *godoc.PageInfo, node func(interface{}) string) map[string]apiSymbol {
+	api := map[string]apiSymbol{}
+	pdoc := info.PDoc
+
+	values := func(values []*doc.Value) {
+		for _, v := range values {
+			// constant specs without type and values repeat those of the
+			// last spec with values, evaluated with their own iota
+			var typ ast.Expr
+			var exprs []ast.Expr
+			for iota, spec := range v.Decl.Specs {
+				vs := spec.(*ast.ValueSpec)
+				if v.Decl.Tok != token.CONST || len(vs.Values) > 0 {
+					typ, exprs = vs.Type, vs.Values
+				}
+				for i, name := range vs.Names {
+					if !name.IsExported() {
+						continue
+					}
+					// the values of variables are not part of the API
+					sym := apiSymbol{sig: v.Decl.Tok.String() + " " + name.Name}
+					if typ != nil {
+						sym.sig += " " + unnamedSignature(node, typ)
+					}
+					sym.decl = sym.sig
+					if v.Decl.Tok == token.CONST && i < len(exprs) {
+						sym.value = replaceIota(node(exprs[i]), iota)
+						sym.decl += " = " + sym.value
+					}
+					api[name.Name] = sym
+				}
+			}
+		}
+	}
+	funcs := func(prefix string, funcs []*doc.Func) {
+		for _, f := range funcs {
+			if !ast.IsExported(f.Name) {
+				continue
+			}
+			sig := unnamedSignature(node, f.Decl.Type)
+			if f.Decl.Recv != nil {
+				// a value receiver adds the method to the method set of
+				// both the type and its pointer
+				sig = node(f.Decl.Recv.List[0].Type) + " " + sig
+			}
+			api[prefix+f.Name] = apiSymbol{decl: node(f.Decl), sig: sig}
+		}
+	}
+
+	values(pdoc.Consts)
+	values(pdoc.Vars)
+	funcs("", pdoc.Funcs)
+	for _, t := range pdoc.Types {
+		// the constants, variables and constructors of unexported types
+		// are still part of the API
+		values(t.Consts)
+		values(t.Vars)
+		funcs("", t.Funcs)
+		if !ast.IsExported(t.Name) {
+			continue
+		}
+
+		for _, spec := range t.Decl.Specs {
+			ts := spec.(*ast.TypeSpec)
+			if ts.Name.Name != t.Name {
+				continue
+			}
+			assign := " "
+			if ts.Assign.IsValid() {
+				assign = " = "
+			}
+
+			switch typ := ts.Type.(type) {
+			case *ast.StructType:
+				decl := "type " + t.Name + assign + "struct"
+				api[t.Name] = apiSymbol{decl: decl, sig: decl}
+				for _, field := range typ.Fields.List {
+					for _, name := range fieldNames(field) {
+						sym := apiSymbol{sig: name + " " + unnamedSignature(node, field.Type)}
+						sym.decl = sym.sig
+						if field.Tag != nil {
+							sym.value = field.Tag.Value
+							sym.decl += " " + sym.value
+						}
+						api[t.Name+"."+name] = sym
+					}
+				}
+			case *ast.InterfaceType:
+				decl := "type " + t.Name + assign + "interface"
+				api[t.Name] = apiSymbol{decl: decl, sig: decl}
+				for _, method := range typ.Methods.List {
+					for _, name := range fieldNames(method) {
+						decl, sig := node(method.Type), unnamedSignature(node, method.Type)
+						if len(method.Names) > 0 {
+							// print the method as a signature, not a func type
+							decl = name + strings.TrimPrefix(decl, "func")
+							sig = name + strings.TrimPrefix(sig, "func")
+						}
+						api[t.Name+"."+name] = apiSymbol{decl: decl, sig: sig, iface: t.Name}
+					}
+				}
+			default:
+				api[t.Name] = apiSymbol{
+					decl: "type " + t.Name + assign + node(ts.Type),
+					sig:  "type " + t.Name + assign + unnamedSignature(node, ts.Type),
+				}
+			}
+		}
+		funcs(t.Name+".", t.Methods)
+	}
+
+	return api
+}
+
+// unnamedSignature prints the type expr with node, with the names of the
+// parameters and results of its func types dropped, as they are not part of
+// the API.
+func unnamedSignature(node func(interface{}) string, expr ast.Expr) string {
+	// the lists are swapped for unnamed copies while printing, innermost
+	// func types last, and restored in reverse order
+	type fieldList struct {
+		list  *ast.FieldList
+		named []*ast.Field
+	}
+	var lists []fieldList
+	ast.Inspect(expr, func(n ast.Node) bool {
+		ft, ok := n.(*ast.FuncType)
+		if !ok {
+			return true
+		}
+		for _, list := range []*ast.FieldList{ft.Params, ft.Results} {
+			if list == nil {
+				continue
+			}
+			lists = append(lists, fieldList{list, list.List})
+			var unnamed []*ast.Field
+			for _, field := range list.List {
+				for i := 0; i < len(field.Names) || i == 0; i++ {
+					unnamed = append(unnamed, &ast.Field{Type: field.Type})
+				}
+			}
+			list.List = unnamed
+		}
+		return true
+	})
+
+	sig := node(expr)
+	for i := len(lists) - 1; i >= 0; i-- {
+		lists[i].list.List = lists[i].named
+	}
+	return sig
+}
+
+// replaceIota replaces the iota identifiers of the constant expression expr
+// with the value n.
+func replaceIota(expr string, n int) string {
+	var s scanner.Scanner
+	fset := token.NewFileSet()
+	s.Init(fset.AddFile("", -1, len(expr)), []byte(expr), nil, 0)
+
+	var b strings.Builder
+	last := 0
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok == token.IDENT && lit == "iota" {
+			offset := fset.Position(pos).Offset
+			b.WriteString(expr[last:offset])
+			b.WriteString(strconv.Itoa(n))
+			last = offset + len(lit)
+		}
+	}
+	b.WriteString(expr[last:])
+	return b.String()
+}
+
+// fieldNames returns the exported names of a struct field, or interface
+// method, including the type name of an embedded one.
+func fieldNames(field *ast.Field) []string {
+	var names []string
+	if len(field.Names) == 0 {
+		typ := field.Type
+		if star, ok := typ.(*ast.StarExpr); ok {
+			typ = star.X
+		}
+		switch ident := typ.(type) {
+		case *ast.Ident:
+			names = append(names, ident.Name)
+		case *ast.SelectorExpr:
+			names = append(names, ident.Sel.Name)
+		}
+	}
+	for _, name := range field.Names {
+		names = append(names, name.Name)
+	}
+
+	exported := names[:0]
+	for _, name := range names {
+		if ast.IsExported(name) {
+			exported = append(exported, name)
+		}
+	}
+	return exported
+}
+
+// DiffAPI compares two versions of the exported API of a package, as
+// returned by extractAPI, and returns the changes ordered by symbol. Changes
+// to the names of parameters and results are not reported.
+func DiffAPI(oldAPI, newAPI map[string]apiSymbol) []APIChange {
+	var changes []APIChange
+	for name, old := range oldAPI {
+		cur, ok := newAPI[name]
+		switch {
+		case !ok:
+			changes = append(changes, APIChange{Kind: APIRemoved, Symbol: name, Old: old.decl, Breaking: true})
+		case cur.sig != old.sig || cur.value != old.value:
+			changes = append(changes, APIChange{Kind: APIChanged, Symbol: name, Old: old.decl, New: cur.decl, Breaking: cur.sig != old.sig})
+		}
+	}
+	for name, cur := range newAPI {
+		if _, ok := oldAPI[name]; !ok {
+			// implementations of a new interface can't be broken
+			_, existed := oldAPI[cur.iface]
+			breaking := cur.iface != "" && existed
+			changes = append(changes, APIChange{Kind: APIAdded, Symbol: name, New: cur.decl, Breaking: breaking})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Symbol < changes[j].Symbol
+	})
+	return changes
+}
+
+func writeChangelog(w io.Writer, pkgPath, oldRev, newRev string, changes []APIChange) {
+	fmt.Fprintf(w, "# API changes of `%s`\n\n", pkgPath)
+	fmt.Fprintf(w, "From `%s` to `%s`.\n\n", oldRev, newRev)
+	if len(changes) == 0 {
+		fmt.Fprint(w, "No changes to the exported API.\n")
+		return
+	}
+
+	breaking := 0
+	for _, change := range changes {
+		if change.Breaking {
+			breaking++
+		}
+	}
+	if breaking > 0 {
+		fmt.Fprintf(w, "> **Likely breaking changes: %d**, marked with :warning:.\n\n", breaking)
+	}
+
+	var sections []string
+	for _, section := range []struct{ kind, title string }{
+		{APIRemoved, "Removed"},
+		{APIChanged, "Changed"},
+		{APIAdded, "Added"},
+	} {
+		var buf bytes.Buffer
+		for _, change := range changes {
+			if change.Kind != section.kind {
+				continue
+			}
+			mark := ""
+			if change.Breaking {
+				mark = ":warning: "
+			}
+			switch change.Kind {
+			case APIRemoved:
+				fmt.Fprintf(&buf, "* %s`%s`: `%s`\n", mark, change.Symbol, oneLine(change.Old))
+			case APIAdded:
+				fmt.Fprintf(&buf, "* %s`%s`: `%s`\n", mark, change.Symbol, oneLine(change.New))
+			case APIChanged:
+				fmt.Fprintf(&buf, "* %s`%s`\n\n  ```diff\n", mark, change.Symbol)
+				for _, line := range strings.Split(change.Old, "\n") {
+					fmt.Fprintf(&buf, "  - %s\n", line)
+				}
+				for _, line := range strings.Split(change.New, "\n") {
+					fmt.Fprintf(&buf, "  + %s\n", line)
+				}
+				fmt.Fprint(&buf, "  ```\n\n")
+			}
+		}
+		if buf.Len() > 0 {
+			sections = append(sections, "## "+section.title+"\n\n"+strings.TrimRight(buf.String(), "\n")+"\n")
+		}
+	}
+	fmt.Fprint(w, strings.Join(sections, "\n"))
+}
@@ -0,0 +1,178 @@
+package godoc2md
+
+import (
+	"bytes"
+	"go/ast"
+	"go/doc"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/godoc"
+)
+
+// sourceAPI returns the exported API of the package of the source file src,
+// loaded with its unexported declarations, as DiffCommand does.
+func sourceAPI(t *testing.T, src string) map[string]apiSymbol {
+	t.Helper()
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pdoc, err := doc.NewFromFiles(fset, []*ast.File{file}, "example.com/p", doc.AllDecls)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info := &godoc.PageInfo{FSet: fset, PDoc: pdoc}
+	return extractAPI(info, func(n interface{}) string {
+		var buf bytes.Buffer
+		if err := printer.Fprint(&buf, fset, n); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	})
+}
+
+func TestDiffAPI(t *testing.T) {
+	for _, tt := range []struct {
+		name, old, new string
+		want           []APIChange
+	}{
+		{
+			name: "parameter rename",
+			old:  "func F(a int) (n int, err error) { return }\nfunc G(f func(x int)) {}",
+			new:  "func F(b int) (int, error) { return 0, nil }\nfunc G(f func(y int)) {}",
+		},
+		{
+			name: "parameter grouping",
+			old:  "func F(a, b int) {}",
+			new:  "func F(a int, b int) {}",
+		},
+		{
+			name: "signature change",
+			old:  "func F(a, b int) {}",
+			new:  "func F(a int) {}",
+			want: []APIChange{{Kind: APIChanged, Symbol: "F", Old: "func F(a, b int)", New: "func F(a int)", Breaking: true}},
+		},
+		{
+			name: "receiver",
+			old:  "type T int\nfunc (t T) M() {}\nfunc (t T) N() {}",
+			new:  "type T int\nfunc (x T) M() {}\nfunc (t *T) N() {}",
+			want: []APIChange{{Kind: APIChanged, Symbol: "T.N", Old: "func (t T) N()", New: "func (t *T) N()", Breaking: true}},
+		},
+		{
+			name: "iota",
+			old:  "type Color int\nconst (\n\tRed Color = iota\n\tGreen\n\tblue\n\tYellow\n)",
+			new:  "type Color int\nconst (\n\tGreen Color = iota\n\tRed\n\tblue\n\tYellow\n)",
+			want: []APIChange{
+				{Kind: APIChanged, Symbol: "Green", Old: "const Green Color = 1", New: "const Green Color = 0"},
+				{Kind: APIChanged, Symbol: "Red", Old: "const Red Color = 0", New: "const Red Color = 1"},
+			},
+		},
+		{
+			name: "constant type",
+			old:  "const C = 1",
+			new:  "const C int64 = 1",
+			want: []APIChange{{Kind: APIChanged, Symbol: "C", Old: "const C = 1", New: "const C int64 = 1", Breaking: true}},
+		},
+		{
+			name: "struct fields",
+			old:  "type S struct {\n\tA int `json:\"a\"`\n\tB int\n\tc int\n}",
+			new:  "type S struct {\n\tA int `json:\"x\"`\n\tB string\n\tc string\n\tD int\n}",
+			want: []APIChange{
+				{Kind: APIChanged, Symbol: "S.A", Old: "A int `json:\"a\"`", New: "A int `json:\"x\"`"},
+				{Kind: APIChanged, Symbol: "S.B", Old: "B int", New: "B string", Breaking: true},
+				{Kind: APIAdded, Symbol: "S.D", New: "D int"},
+			},
+		},
+		{
+			name: "interface method added",
+			old:  "type I interface {\n\tM(a int)\n}",
+			new:  "type I interface {\n\tM(b int)\n\tN()\n}",
+			want: []APIChange{{Kind: APIAdded, Symbol: "I.N", New: "N()", Breaking: true}},
+		},
+		{
+			name: "new interface",
+			old:  "func F() {}",
+			new:  "func F() {}\ntype I interface {\n\tM()\n\tfmt.Stringer\n}",
+			want: []APIChange{
+				{Kind: APIAdded, Symbol: "I", New: "type I interface"},
+				{Kind: APIAdded, Symbol: "I.M", New: "M()"},
+				{Kind: APIAdded, Symbol: "I.Stringer", New: "fmt.Stringer"},
+			},
+		},
+		{
+			name: "removed",
+			old:  "func F() {}\nvar V int",
+			new:  "",
+			want: []APIChange{
+				{Kind: APIRemoved, Symbol: "F", Old: "func F()", Breaking: true},
+				{Kind: APIRemoved, Symbol: "V", Old: "var V int", Breaking: true},
+			},
+		},
+		{
+			name: "unexported type",
+			old:  "type t int\nconst C t = 1\nfunc New() t { return 0 }\nfunc (t) M() {}",
+			new:  "type t int\nconst C t = 2\nfunc New() t { return 0 }\nfunc (t) M(int) {}",
+			want: []APIChange{{Kind: APIChanged, Symbol: "C", Old: "const C t = 1", New: "const C t = 2"}},
+		},
+	} {
+		oldAPI := sourceAPI(t, "package p\n"+tt.old)
+		newAPI := sourceAPI(t, "package p\n"+tt.new)
+		if got := DiffAPI(oldAPI, newAPI); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: DiffAPI =\n%+v\nwant\n%+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReplaceIota(t *testing.T) {
+	for _, tt := range []struct {
+		expr string
+		n    int
+		want string
+	}{
+		{"iota", 3, "3"},
+		{"1 << iota", 2, "1 << 2"},
+		{"iota * (iota + 1)", 2, "2 * (2 + 1)"},
+		{"iotas + iota_", 1, "iotas + iota_"},
+		{`"iota"`, 1, `"iota"`},
+		{"KB << (10 * iota)", 0, "KB << (10 * 0)"},
+	} {
+		if got := replaceIota(tt.expr, tt.n); got != tt.want {
+			t.Errorf("replaceIota(%q, %d) = %q, want %q", tt.expr, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestFieldNames(t *testing.T) {
+	src := `package p
+
+type S struct {
+	A, b int
+	c    string
+	T
+	*U
+	fmt.Stringer
+	*io.Reader
+	v
+}
+`
+	file, err := parser.ParseFile(token.NewFileSet(), "p.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := file.Decls[0].(*ast.GenDecl).Specs[0].(*ast.TypeSpec).Type.(*ast.StructType)
+
+	var got [][]string
+	for _, field := range st.Fields.List {
+		got = append(got, fieldNames(field))
+	}
+	want := [][]string{{"A"}, {}, {"T"}, {"U"}, {"Stringer"}, {"Reader"}, {}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("fieldNames = %q, want %q", got, want)
+	}
+}
@@ -26,8 +26,50 @@ func main() {
 	pres := godoc2md.NewPresentation(corpus, config)
 	output := os.Stdout
 
-	if err := godoc.CommandLine(output, fs, pres, args); err != nil {
-		log.Print(err)
+	if args[0] == godoc2md.TemplateCmd {
+		if err := godoc2md.TemplateCommand(output, pres, config, args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if args[0] == godoc2md.CoverageCmd {
+		if err := godoc2md.CoverageCommand(output, fs, pres, config, args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if args[0] == godoc2md.DiffCmd {
+		if err := godoc2md.DiffCommand(output, fs, pres, config, args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if args[0] == godoc2md.ServeCmd {
+		if err := godoc2md.ServeCommand(fs, pres, config, args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if *config.Watch {
+		if err := godoc2md.Watch(fs, pres, config, args); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if *config.OutDir != "" {
+		if err := godoc2md.WriteSite(fs, pres, config, args); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if err := godoc2md.CommandLine(output, fs, pres, config, args); err != nil {
+		log.Fatal(err)
 	}
 }
 
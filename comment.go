@@ -7,10 +7,10 @@
 package godoc2md
 
 import (
+	"bytes"
 	"io"
 	"regexp"
 	"strings"
-	"text/template" // for HTMLEscape
 	"unicode"
 	"unicode/utf8"
 )
@@ -30,17 +30,6 @@ const (
 
 var matchRx = regexp.MustCompile(`(` + urlRx + `)|(` + identRx + `)`)
 
-var (
-	htmlA    = []byte(`<a href="`)
-	htmlAq   = []byte(`">`)
-	htmlEnda = []byte("</a>")
-
-	mdPre     = []byte("")
-	mdPreline = []byte("```\n")
-	mdNewline = []byte("\n")
-	mdH3      = []byte("### ")
-)
-
 // ToMD converts comment text to formatted Markdown. The comment was prepared by
 // DocReader, so it is known not to have leading, trailing blank lines nor to
 // have trailing spaces at the end of lines. The comment markers have already
@@ -51,36 +40,45 @@ var (
 // followed by another paragraph span, begins with a capital letter, and
 // contains no punctuation is formatted as a heading.
 //
-// A span of indented lines is converted into a `<pre>` block, with the common
-// indent prefix removed.
+// A span of indented lines is converted into a fenced code block, with the
+// common indent prefix removed.
 //
-// URLs in the comment text are converted into links.
+// URLs in the comment text, code blocks included, are converted into links,
+// and headings are given an explicit anchor, slugged the way GitHub would.
 func ToMD(w io.Writer, text string) {
+	RenderComment(w, text, MarkdownRenderer{}, NewAnchors(ForgeGitHub))
+}
+
+// RenderComment converts comment text, following the same rules as ToMD, into
+// the markup written by the provided Renderer. Indented spans whose first
+// line starts with a list marker are converted into lists, unless the
+// Renderer writes indented spans itself. Heading ids are taken from anchors,
+// so that they are unique across all the comments of a page.
+func RenderComment(w io.Writer, text string, r Renderer, anchors *Anchors) {
 	for _, b := range blocks(text) {
 		switch b.op {
 		case opPara:
+			var buf bytes.Buffer
 			for _, line := range b.lines {
-				emphasize(w, line)
+				emphasize(&buf, r, line)
 			}
-			_, _ = w.Write(mdNewline) // trailing newline to emulate </p>
+			r.Paragraph(w, buf.String())
 		case opHead:
-			_, _ = w.Write(mdH3)
-			id := ""
-			for _, line := range b.lines {
-				if id == "" {
-					id = anchorID(line)
+			r.Heading(w, b.lines[0], anchors.Heading(b.lines[0]))
+		case opPre, opList:
+			if pr, ok := r.(preRenderer); ok {
+				pr.Pre(w, b.lines)
+			} else if b.op == opPre {
+				r.CodeBlock(w, "", b.lines)
+			} else {
+				items, ordered := listItems(b.lines)
+				for i, item := range items {
+					var buf bytes.Buffer
+					emphasize(&buf, r, item)
+					items[i] = buf.String()
 				}
-				_, _ = w.Write([]byte(line))
-			}
-			_, _ = w.Write(mdNewline)
-		case opPre:
-			_, _ = w.Write(mdPreline)
-			for _, line := range b.lines {
-				// _, _ = w.Write(mdPre)
-				emphasize(w, line)
+				r.List(w, items, ordered)
 			}
-			_, _ = w.Write(mdPreline)
-			_, _ = w.Write(mdNewline)
 		}
 	}
 }
@@ -125,8 +123,9 @@ func heading(line string) string {
 	return line
 }
 
-// Emphasize and escape a line of text for HTML. URLs are converted into links.
-func emphasize(w io.Writer, line string) {
+// Emphasize and escape a line of text for the renderer. URLs are converted
+// into links.
+func emphasize(w io.Writer, r Renderer, line string) {
 	for {
 		m := matchRx.FindStringSubmatchIndex(line)
 		if m == nil {
@@ -135,29 +134,22 @@ func emphasize(w io.Writer, line string) {
 		// m >= 6 (two parenthesized sub-regexps in matchRx, 1st one is urlRx)
 
 		// write text before match
-		_, _ = w.Write([]byte(line[0:m[0]]))
+		_, _ = io.WriteString(w, r.Escape(line[0:m[0]]))
 
 		// analyze match
 		match := line[m[0]:m[1]]
 
-		// if URL then write as link
+		// if URL then write as link, otherwise write match
 		if m[2] >= 0 {
-			_, _ = w.Write(htmlA)
-			template.HTMLEscape(w, []byte(match))
-			_, _ = w.Write(htmlAq)
-		}
-
-		// write match
-		_, _ = w.Write([]byte(match))
-
-		if m[2] >= 0 {
-			_, _ = w.Write(htmlEnda)
+			_, _ = io.WriteString(w, r.Link(match, match))
+		} else {
+			_, _ = io.WriteString(w, r.Escape(match))
 		}
 
 		// advance
 		line = line[m[1]:]
 	}
-	_, _ = w.Write([]byte(line))
+	_, _ = io.WriteString(w, r.Escape(line))
 }
 
 func indentLen(s string) int {
@@ -202,19 +194,13 @@ func unindent(block []string) {
 	}
 }
 
-var nonAlphaNumRx = regexp.MustCompile(`[^a-zA-Z0-9]`)
-
-func anchorID(line string) string {
-	// Add a "hdr-" prefix to avoid conflicting with IDs used for package symbols.
-	return "hdr-" + nonAlphaNumRx.ReplaceAllString(line, "_")
-}
-
 type op int
 
 const (
 	opPara op = iota
 	opHead
 	opPre
+	opList
 )
 
 type block struct {
@@ -222,6 +208,34 @@ type block struct {
 	lines []string
 }
 
+// listMarkerRx matches the bullet ("-", "*", "+", "•") or number ("1.", "1)")
+// starting a list item.
+var listMarkerRx = regexp.MustCompile(`^([-*+•]|([0-9]+)[.)])[ \t]+`)
+
+// listItems splits an unindented span of lines into the text of its list
+// items. Continuation lines are joined onto the item they follow.
+func listItems(lines []string) (items []string, ordered bool) {
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if m := listMarkerRx.FindStringSubmatch(line); m != nil {
+			if len(items) == 0 {
+				ordered = m[2] != ""
+			}
+			items = append(items, line[len(m[0]):])
+			continue
+		}
+		if len(items) == 0 {
+			items = append(items, line)
+			continue
+		}
+		items[len(items)-1] += " " + line
+	}
+	return items, ordered
+}
+
 func blocks(text string) []block {
 	var (
 		out  []block
@@ -233,7 +247,7 @@ func blocks(text string) []block {
 
 	close := func() {
 		if para != nil {
-			out = append(out, block{opPara, para})
+			out = append(out, block{op: opPara, lines: para})
 			para = nil
 		}
 	}
@@ -267,8 +281,13 @@ func blocks(text string) []block {
 
 			unindent(pre)
 
+			// a span starting with a list marker is a list, otherwise
 			// put those lines in a pre block
-			out = append(out, block{opPre, pre})
+			if listMarkerRx.MatchString(pre[0]) {
+				out = append(out, block{op: opList, lines: pre})
+			} else {
+				out = append(out, block{op: opPre, lines: pre})
+			}
 			lastWasHeading = false
 			continue
 		}
@@ -280,7 +299,7 @@ func blocks(text string) []block {
 			// might be a heading.
 			if head := heading(line); head != "" {
 				close()
-				out = append(out, block{opHead, []string{head}})
+				out = append(out, block{op: opHead, lines: []string{head}})
 				i += 2
 				lastWasHeading = true
 				continue
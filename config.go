@@ -1,7 +1,6 @@
 package godoc2md
 
 import (
-	"bufio"
 	"flag"
 	"fmt"
 	"log"
@@ -27,40 +26,47 @@ var (
 		BasePrefix:        flag.String("basePrefix", "", "path prefix of go files. If not set, cli will attempt to set it by checking `go.mod`, current directory, and the 1st position argument"),
 		UrlPrefix:         flag.String("urlPrefix", defaultURLPrefix, "URL for generated URLs."),
 		SourceID:          flag.String("sourceID", defaultSourceID, "URL for generated URLs."),
-		AltPkgTemplate:    flag.String("template", "", "path to an alternate template file"),
+		AltPkgTemplate:    flag.String("template", "", "path to a template file, or directory of template files, overriding blocks of the built-in template. Only the md template has blocks, those of other formats must be replaced entirely"),
+		Model:             flag.Bool("model", false, "execute the template with the stable godoc2md.Package model instead of godoc.PageInfo. Requires a -template replacing the built-in template, as its blocks are executed with godoc.PageInfo"),
 		ShowPlayground:    flag.Bool("play", true, "enable playground in web interface"),
 		ShowExamples:      flag.Bool("ex", false, "show examples in command line mode"),
 		DeclLinks:         flag.Bool("links", true, "link identifiers to their declarations"),
 		SrcLinkHashFormat: flag.String("hashformat", "#L%d", "source link URL hash format"),
+		NotesRx:           flag.String("notes", "BUG", "regular expression matching the note markers (BUG, TODO, etc.) to render"),
+		Format:            flag.String("format", "md", "output format, one of: adoc, html, json, md, rst"),
+		OutDir:            flag.String("out", "", "directory to write one page per package into. If set, every positional argument is documented as a package"),
+		HideDeprecated:    flag.Bool("hideDeprecated", false, "omit symbols marked as deprecated"),
+		Admonitions:       flag.Bool("admonitions", false, "render callouts, such as deprecation notices, with GitHub [!WARNING] admonition syntax"),
+		CheckLinks:        flag.String("checkLinks", "", "report internal links without a target in the output: warn, or error to fail"),
+		MinCoverage:       flag.Float64("minCoverage", 0, "percentage of documented symbols below which the coverage subcommand fails"),
+		Forge:             flag.String("forge", "github", "forge whose heading slugs anchors follow, one of: bitbucket, github, gitlab"),
+		Order:             flag.String("order", "name", "order of the symbols: name, source, or source grouped under a heading per file, or per \"Section: Title\" comment"),
+		Split:             flag.Bool("split", false, "with -out, write every exported type, and the package level functions, to a page of its own next to the package page"),
+		Tags:              flag.String("tags", "", "comma separated list of build tags to consider satisfied when loading packages"),
+		GOOS:              flag.String("goos", "", "target operating system to load packages for. Defaults to GOOS"),
+		GOARCH:            flag.String("goarch", "", "target architecture to load packages for. Defaults to GOARCH"),
+		Platforms:         flag.String("platforms", "", "comma separated list of goos/goarch platforms to load packages for, documenting the symbols of all of them and noting those missing from some. Symbols are documented as declared on the first platform they exist on"),
+		ModZip:            flag.String("modzip", "", "module zip file to read the packages from, at the version it holds, instead of the module cache used for package@version arguments"),
+		Rev:               flag.String("rev", "", "git revision, such as a tag or a commit, to read the packages of the repository at instead of the working tree, pinning source links to its commit"),
+		Watch:             flag.Bool("watch", false, "with -out, keep running and rewrite the pages of the packages whose .go files, or template, change"),
+		HTTP:              flag.String("http", "localhost:6060", "loopback address the serve subcommand listens on"),
+		Parallel:          flag.Int("parallel", 0, "number of packages a multi-package run renders concurrently. Defaults to the number of CPUs"),
+		CacheDir:          flag.String("cache", "", "directory caching the pages of multi-package runs, skipping the packages whose sources, template and flags are unchanged"),
+		Site:              flag.String("site", "", "static site generator preset adding front matter and navigation files to the -out pages, one of: docusaurus, hugo, jekyll, mkdocs"),
 	}
 )
 
 func usage() {
-	fmt.Fprintf(os.Stderr, "usage: %s package [more-packages ...]\n", cmdName)
+	fmt.Fprintf(os.Stderr, "usage: %s package[@version] [more-packages ...]\n", cmdName)
+	fmt.Fprintf(os.Stderr, "       %s template dump | check <path>\n", cmdName)
+	fmt.Fprintf(os.Stderr, "       %s coverage package [more-packages ...]\n", cmdName)
+	fmt.Fprintf(os.Stderr, "       %s diff <old> <new> package\n", cmdName)
+	fmt.Fprintf(os.Stderr, "       %s serve package [more-packages ...]\n", cmdName)
 	flag.PrintDefaults()
 	os.Exit(2)
 }
 
 func getBasePkgPrefix(potench string) *string {
-	cwd, _ := os.Getwd()
-	modfilePath := path.Join(cwd, "go.mod")
-
-	// Check if we have a go.mod
-	if _, err := os.Stat(modfilePath); err == nil || !os.IsNotExist(err) {
-		file, err := os.Open(modfilePath)
-		if err != nil {
-			log.Fatalf("failed to open %s: %v", modfilePath, err)
-		}
-
-		fileBuf := bufio.NewReader(file)
-		nlByte := []byte("\n")
-		l1, err := fileBuf.ReadString(nlByte[0])
-		if err == nil && l1 != "" {
-			tmpString := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(l1, "module "), "\n"))
-			return &tmpString
-		}
-	}
-
 	// Try and guess the package path
 	p := os.Getenv("GOPATH")
 	if p != "" {
@@ -84,14 +90,92 @@ type Cli struct {
 	UrlPrefix      *string
 	SourceID       *string
 	AltPkgTemplate *string
+	// Model executes the template with the Package model, which is stable
+	// across releases, rather than with godoc's PageInfo.
+	Model          *bool
 	ShowPlayground *bool
 	ShowExamples   *bool
 	DeclLinks      *bool
 
+	// Format selects the built-in template (and page filename) used for the
+	// output. When OutDir is set, each package in Packages is written to its
+	// own page below it instead of to stdout.
+	Format   *string
+	OutDir   *string
+	Packages []string
+	// Watch keeps rewriting the pages below OutDir as their sources change.
+	Watch *bool
+	// Parallel is the number of packages rendered at once into OutDir, and
+	// CacheDir keeps their pages across runs, keyed by their sources, the
+	// template and the flags.
+	Parallel *int
+	CacheDir *string
+	// Order sorts the symbols by name or source position, and optionally
+	// groups the functions and types by source file or section comment.
+	Order *string
+	// Split writes the functions and types of each package to pages of
+	// their own, linked from the index of the package page.
+	Split *bool
+	// Tags, GOOS and GOARCH set up the build context packages are loaded
+	// with, and so which of their files are documented. Platforms loads
+	// them for several platforms instead, noting the symbols only some of
+	// them declare.
+	Tags      *string
+	GOOS      *string
+	GOARCH    *string
+	Platforms *string
+
+	// Workspace is the go.work workspace of the current directory or,
+	// outside of workspaces, its main module, if any. Packages of its
+	// modules are loaded from the module directories, and link to their
+	// sources below the directory of their module in the repository.
+	Workspace *Workspace
+
+	// Versions are the module versions, read from the module cache or from
+	// the ModZip file, that the packages given as "importPath@version"
+	// belong to. They are opened as the packages are loaded, and their
+	// source links are pinned to the tag of the version.
+	ModZip   *string
+	Versions []*ModuleVersion
+
+	// Rev reads the packages of the repository from the git object store,
+	// as of the Revision it names, rather than from the working tree.
+	Rev      *string
+	Revision *Revision
+
+	// Site selects a static site generator preset, writing front matter
+	// into every page and the generator's navigation files into OutDir.
+	Site *string
+
+	// Notes are comments of the form `MARKER(uid): body`. Only the markers
+	// matched by NotesRx are collected.
+	NotesRx *string
+
 	// The hash format for Github is the default `#L%d`; but other source control platforms do not
 	// use the same format. For example Bitbucket Enterprise uses `#%d`. This option provides the
 	// user the option to switch the format as needed and still remain backwards compatible.
 	SrcLinkHashFormat *string
+
+	// Symbols documented with a `Deprecated:` paragraph are flagged in the
+	// index and their notice is rendered as a callout. HideDeprecated drops
+	// them from the output entirely.
+	HideDeprecated *bool
+	Admonitions    *bool
+
+	// Forge selects the slug algorithm of heading anchors, so that links to
+	// headings match the ids the forge renders.
+	Forge *string
+
+	// CheckLinks checks the internal links of the output once it's written,
+	// and either warns about dangling ones or fails.
+	CheckLinks *string
+
+	// MinCoverage is the documentation coverage, in percent, the coverage
+	// subcommand requires.
+	MinCoverage *float64
+
+	// HTTP is the address the serve subcommand listens on.
+	HTTP *string
 }
 
 func Parse() ([]string, *Cli) {
@@ -108,9 +192,39 @@ func Parse() ([]string, *Cli) {
 		Config.Goroot = &root
 	}
 
+	// outside of workspaces, the base prefix is the path of the main
+	// module, and of the workspace repository otherwise
+	var prefix string
+	cwd, _ := os.Getwd()
+	if workFile := findWorkFile(cwd); workFile != "" {
+		ws, err := LoadWorkspace(workFile)
+		if err != nil {
+			log.Fatalf("failed to load workspace: %v", err)
+		}
+		Config.Workspace = ws
+		prefix = ws.repoPrefix()
+	} else if modFile := findModFile(cwd); modFile != "" {
+		ws, err := LoadModule(modFile)
+		if err != nil {
+			log.Fatalf("failed to load module: %v", err)
+		}
+		Config.Workspace = ws
+		prefix = ws.Modules[0].Path
+	}
+
 	if *Config.BasePrefix == "" {
-		Config.BasePrefix = getBasePkgPrefix(args[0])
+		if prefix != "" {
+			Config.BasePrefix = &prefix
+		} else {
+			Config.BasePrefix = getBasePkgPrefix(args[0])
+		}
 	}
 
+	if *Config.OutDir != "" {
+		Config.Packages = args
+	}
+
+	configureBuild(Config)
+
 	return args, Config
 }
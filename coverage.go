@@ -0,0 +1,300 @@
+package godoc2md
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/token"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/godoc"
+	"golang.org/x/tools/godoc/vfs"
+)
+
+// CoverageCmd is the name of the subcommand reporting documentation
+// coverage, as in `godoc2md coverage <package> [more-packages ...]`.
+const CoverageCmd = "coverage"
+
+// Coverage is the documentation coverage of a set of packages.
+type Coverage struct {
+	Packages   []PackageCoverage `json:"packages"`
+	Documented int               `json:"documented"`
+	Total      int               `json:"total"`
+	Percent    float64           `json:"percent"`
+}
+
+// PackageCoverage is the documentation coverage of the exported API of a
+// single package. The package doc comment counts as one of its symbols.
+type PackageCoverage struct {
+	ImportPath string     `json:"importPath"`
+	Documented int        `json:"documented"`
+	Total      int        `json:"total"`
+	Percent    float64    `json:"percent"`
+	Issues     []DocIssue `json:"issues"`
+}
+
+// DocIssue is a problem found with the doc comment of a symbol.
+type DocIssue struct {
+	// Symbol is the name of the symbol, with fields and methods qualified
+	// by their type, as in "Type.Method".
+	Symbol string `json:"symbol"`
+	// Kind is one of package, const, var, func, type, method or field.
+	Kind    string `json:"kind"`
+	Problem string `json:"problem"`
+	// File is the source file of the symbol, joined to the import path of
+	// its package.
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+func (i DocIssue) String() string {
+	if i.Line == 0 {
+		return fmt.Sprintf("%s: %s %s: %s", i.File, i.Kind, i.Symbol, i.Problem)
+	}
+	return fmt.Sprintf("%s:%d: %s %s: %s", i.File, i.Line, i.Kind, i.Symbol, i.Problem)
+}
+
+const (
+	problemMissing    = "missing doc comment"
+	problemNoSynopsis = "package doc comment has no synopsis"
+)
+
+// CoverageCommand reports the documentation coverage of the packages named by
+// args to w, as text or, with `-format json`, as JSON. Packages are loaded as
+// for rendering, so the workspace, platform, revision and version flags
+// apply. It fails if the total coverage is below the `-minCoverage`
+// percentage.
+func CoverageCommand(w io.Writer, fs vfs.NameSpace, pres *godoc.Presentation, cfg *Cli, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: %s %s package [more-packages ...]", cmdName, CoverageCmd)
+	}
+
+	var cov Coverage
+	for _, arg := range args {
+		info, err := loadPage(fs, pres, cfg, []string{arg})
+		if err != nil {
+			return err
+		}
+		if info.PDoc == nil {
+			return fmt.Errorf("%s: no package documentation", arg)
+		}
+
+		pkg := NewPackageCoverage(info)
+		cov.Packages = append(cov.Packages, pkg)
+		cov.Documented += pkg.Documented
+		cov.Total += pkg.Total
+	}
+	cov.Percent = percent(cov.Documented, cov.Total)
+
+	if *cfg.Format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(cov); err != nil {
+			return err
+		}
+	} else {
+		writeCoverage(w, cov)
+	}
+
+	if cov.Percent < *cfg.MinCoverage {
+		return fmt.Errorf("documentation coverage %.1f%% is below the minimum of %.1f%%", cov.Percent, *cfg.MinCoverage)
+	}
+	return nil
+}
+
+func writeCoverage(w io.Writer, cov Coverage) {
+	for _, pkg := range cov.Packages {
+		for _, issue := range pkg.Issues {
+			fmt.Fprintln(w, issue)
+		}
+	}
+	for _, pkg := range cov.Packages {
+		fmt.Fprintf(w, "%s: %.1f%% documented (%d/%d)\n", pkg.ImportPath, pkg.Percent, pkg.Documented, pkg.Total)
+	}
+	if len(cov.Packages) > 1 {
+		fmt.Fprintf(w, "total: %.1f%% documented (%d/%d)\n", cov.Percent, cov.Documented, cov.Total)
+	}
+}
+
+func percent(documented, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return 100 * float64(documented) / float64(total)
+}
+
+// NewPackageCoverage checks the doc comments of the package documented by
+// info, and of all of its exported symbols, struct fields included.
+func NewPackageCoverage(info *godoc.PageInfo) PackageCoverage {
+	c := coverageChecker{
+		fset: info.FSet,
+		pkg:  PackageCoverage{ImportPath: info.PDoc.ImportPath, Issues: []DocIssue{}},
+	}
+	pdoc := info.PDoc
+
+	var pos token.Pos
+	filenames := make([]string, 0, len(info.PAst))
+	for filename := range info.PAst {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+	for _, filename := range filenames {
+		if file := info.PAst[filename]; pos == token.NoPos || file.Doc != nil {
+			pos = file.Package
+			if file.Doc != nil {
+				break
+			}
+		}
+	}
+	switch {
+	case pdoc.Doc == "":
+		c.issue("package", pdoc.Name, pos, problemMissing)
+	case doc.Synopsis(pdoc.Doc) == "":
+		c.issue("package", pdoc.Name, pos, problemNoSynopsis)
+	default:
+		c.pkg.Documented++
+	}
+	c.pkg.Total++
+
+	c.values("const", pdoc.Consts)
+	c.values("var", pdoc.Vars)
+	c.funcs("func", "", pdoc.Funcs)
+	for _, t := range pdoc.Types {
+		c.check("type", t.Name, t.Name, t.Doc, t.Decl.Pos())
+		c.fields(t)
+		c.values("const", t.Consts)
+		c.values("var", t.Vars)
+		c.funcs("func", "", t.Funcs)
+		c.funcs("method", t.Name, t.Methods)
+	}
+
+	sort.SliceStable(c.pkg.Issues, func(i, j int) bool {
+		a, b := c.pkg.Issues[i], c.pkg.Issues[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	c.pkg.Percent = percent(c.pkg.Documented, c.pkg.Total)
+	return c.pkg
+}
+
+type coverageChecker struct {
+	fset *token.FileSet
+	pkg  PackageCoverage
+}
+
+// check counts a symbol, and records an issue if its doc comment is missing
+// or, if name is set, doesn't start with it.
+func (c *coverageChecker) check(kind, symbol, name, text string, pos token.Pos) {
+	c.pkg.Total++
+	switch {
+	case strings.TrimSpace(text) == "":
+		c.issue(kind, symbol, pos, problemMissing)
+	case name != "" && !startsWithName(text, name):
+		c.issue(kind, symbol, pos, fmt.Sprintf("doc comment should start with %q", name))
+	default:
+		c.pkg.Documented++
+	}
+}
+
+func (c *coverageChecker) issue(kind, symbol string, pos token.Pos, problem string) {
+	position := c.fset.Position(pos)
+	file := c.pkg.ImportPath
+	if position.Filename != "" {
+		file = path.Join(file, path.Base(position.Filename))
+	}
+	c.pkg.Issues = append(c.pkg.Issues, DocIssue{
+		Symbol:  symbol,
+		Kind:    kind,
+		Problem: problem,
+		File:    file,
+		Line:    position.Line,
+	})
+}
+
+// values checks constant and variable declarations. A group is documented by
+// its doc comment, or by a comment on every one of its specs; only the doc
+// comment of an ungrouped declaration has to start with its name.
+func (c *coverageChecker) values(kind string, values []*doc.Value) {
+	for _, v := range values {
+		if v.Doc == "" && v.Decl.Lparen.IsValid() && specsCommented(v.Decl) {
+			c.pkg.Total++
+			c.pkg.Documented++
+			continue
+		}
+		name := ""
+		if !v.Decl.Lparen.IsValid() && len(v.Names) == 1 {
+			name = v.Names[0]
+		}
+		c.check(kind, strings.Join(v.Names, ", "), name, v.Doc, v.Decl.Pos())
+	}
+}
+
+func specsCommented(decl *ast.GenDecl) bool {
+	for _, spec := range decl.Specs {
+		vs, ok := spec.(*ast.ValueSpec)
+		if !ok || vs.Doc == nil && vs.Comment == nil {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *coverageChecker) funcs(kind, typeName string, funcs []*doc.Func) {
+	for _, f := range funcs {
+		symbol := f.Name
+		if typeName != "" {
+			symbol = typeName + "." + f.Name
+		}
+		c.check(kind, symbol, f.Name, f.Doc, f.Decl.Pos())
+	}
+}
+
+// fields checks the exported, non-embedded, fields of a struct type.
+func (c *coverageChecker) fields(t *doc.Type) {
+	for _, spec := range t.Decl.Specs {
+		ts, ok := spec.(*ast.TypeSpec)
+		if !ok || ts.Name.Name != t.Name {
+			continue
+		}
+		st, ok := ts.Type.(*ast.StructType)
+		if !ok {
+			continue
+		}
+		for _, field := range st.Fields.List {
+			text := field.Doc.Text() + field.Comment.Text()
+			for _, name := range field.Names {
+				if name.IsExported() {
+					c.check("field", t.Name+"."+name.Name, "", text, name.Pos())
+				}
+			}
+		}
+	}
+}
+
+// startsWithName reports whether the doc comment text starts with name,
+// optionally preceded by an article, as in "A Reader reads...".
+func startsWithName(text, name string) bool {
+	for _, article := range []string{"", "A ", "An ", "The "} {
+		rest := strings.TrimPrefix(text, article)
+		if len(rest) == len(text) && article != "" {
+			continue
+		}
+		if strings.HasPrefix(rest, name) {
+			next := strings.TrimPrefix(rest, name)
+			if next == "" || !isIdentRune(next[0]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isIdentRune(b byte) bool {
+	return b == '_' || '0' <= b && b <= '9' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z'
+}
@@ -0,0 +1,200 @@
+package godoc2md
+
+import (
+	"go/ast"
+	"go/doc"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/godoc"
+)
+
+// sourcePage returns the documentation of the package of the source file
+// src, filtered to its exported API as godoc loads it.
+func sourcePage(t *testing.T, src string) *godoc.PageInfo {
+	t.Helper()
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pdoc, err := doc.NewFromFiles(fset, []*ast.File{file}, "example.com/p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &godoc.PageInfo{FSet: fset, PDoc: pdoc, PAst: map[string]*ast.File{"p.go": file}}
+}
+
+func TestStartsWithName(t *testing.T) {
+	for _, tt := range []struct {
+		text, name string
+		want       bool
+	}{
+		{"Foo does things.", "Foo", true},
+		{"Foo", "Foo", true},
+		{"Foo's value.", "Foo", true},
+		{"Foo, unlike Bar, does things.", "Foo", true},
+		{"A Foo does things.", "Foo", true},
+		{"An Foo does things.", "Foo", true},
+		{"The Foo does things.", "Foo", true},
+		{"Foobar does things.", "Foo", false},
+		{"Foo_bar does things.", "Foo", false},
+		{"Foo2 does things.", "Foo", false},
+		{"A Foobar does things.", "Foo", false},
+		{"AFoo does things.", "Foo", false},
+		{"a Foo does things.", "Foo", false},
+		{"the Foo does things.", "Foo", false},
+		{"Returns a Foo.", "Foo", false},
+		{" Foo does things.", "Foo", false},
+		{"Actually does things.", "A", false},
+		{"A does things.", "A", true},
+		{"An does things.", "An", true},
+	} {
+		if got := startsWithName(tt.text, tt.name); got != tt.want {
+			t.Errorf("startsWithName(%q, %q) = %v, want %v", tt.text, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNewPackageCoverage(t *testing.T) {
+	for _, tt := range []struct {
+		name              string
+		src               string
+		documented, total int
+		issues            []string
+	}{
+		{
+			name: "documented",
+			src: `// Package p does things.
+package p
+
+// F does things.
+func F() {}
+
+// A T is a thing.
+type T struct {
+	// X is a field.
+	X int
+	Y int // Y is a field.
+	y int
+}
+
+// An Err is an error.
+var Err error
+
+// New returns a T.
+func New() T { return T{} }
+
+// M does things.
+func (T) M() {}
+
+func (T) m() {}
+`,
+			documented: 8, total: 8,
+		},
+		{
+			name: "missing and misnamed",
+			src: `// Package p does things.
+package p
+
+func F() {}
+
+// Does things.
+func G() {}
+
+// Handler handles things.
+type Handle struct {
+	X, Y int
+}
+
+// Mode does things.
+func (Handle) M() {}
+`,
+			documented: 1, total: 7,
+			issues: []string{
+				"example.com/p/p.go:4: func F: missing doc comment",
+				"example.com/p/p.go:7: func G: doc comment should start with \"G\"",
+				"example.com/p/p.go:10: type Handle: doc comment should start with \"Handle\"",
+				"example.com/p/p.go:11: field Handle.X: missing doc comment",
+				"example.com/p/p.go:11: field Handle.Y: missing doc comment",
+				"example.com/p/p.go:15: method Handle.M: doc comment should start with \"M\"",
+			},
+		},
+		{
+			name: "grouped values",
+			src: `// Package p does things.
+package p
+
+// The limits of things.
+const (
+	Min = 0
+	Max = 9
+)
+
+const (
+	// Red is red.
+	Red = iota
+	Green // Green is green.
+)
+
+const (
+	// Up is up.
+	Up   = 0
+	Down = 1
+)
+
+// Errors of a multi-name declaration need not start with a name.
+var ErrA, ErrB error
+
+// Sets the default.
+var Default = 1
+
+var (
+	V = 1
+)
+
+// W is documented.
+var W = 1
+`,
+			documented: 5, total: 8,
+			issues: []string{
+				"example.com/p/p.go:16: const Up, Down: missing doc comment",
+				"example.com/p/p.go:26: var Default: doc comment should start with \"Default\"",
+				"example.com/p/p.go:28: var V: missing doc comment",
+			},
+		},
+		{
+			name: "no package doc",
+			src: `package p
+
+// F does things.
+func F() {}
+`,
+			documented: 1, total: 2,
+			issues: []string{"example.com/p/p.go:1: package p: missing doc comment"},
+		},
+		{
+			name: "no synopsis",
+			src: `// Copyright 2024 The Authors. All rights reserved.
+package p
+`,
+			documented: 0, total: 1,
+			issues: []string{"example.com/p/p.go:2: package p: package doc comment has no synopsis"},
+		},
+	} {
+		cov := NewPackageCoverage(sourcePage(t, tt.src))
+
+		var issues []string
+		for _, issue := range cov.Issues {
+			issues = append(issues, issue.String())
+		}
+		if !reflect.DeepEqual(issues, tt.issues) {
+			t.Errorf("%s: issues =\n%q\nwant\n%q", tt.name, issues, tt.issues)
+		}
+		if cov.Documented != tt.documented || cov.Total != tt.total {
+			t.Errorf("%s: documented %d/%d, want %d/%d", tt.name, cov.Documented, cov.Total, tt.documented, tt.total)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package godoc2md
+
+import (
+	"bytes"
+	"go/doc"
+	"strings"
+
+	"golang.org/x/tools/godoc"
+)
+
+const deprecatedPrefix = "Deprecated: "
+
+// splitDeprecation splits a doc comment into its regular body and the text of
+// its `Deprecated:` paragraph, following the convention described at
+// https://go.dev/wiki/Deprecated. The returned notice has the prefix removed
+// and is empty if the comment does not mark a deprecation.
+func splitDeprecation(text string) (body, notice string) {
+	var paras []string
+	for _, para := range strings.Split(text, "\n\n") {
+		if notice == "" && strings.HasPrefix(para, deprecatedPrefix) {
+			notice = strings.TrimSpace(strings.TrimPrefix(para, deprecatedPrefix))
+			continue
+		}
+		paras = append(paras, para)
+	}
+	if notice == "" {
+		return text, ""
+	}
+
+	return strings.Join(paras, "\n\n"), notice
+}
+
+// isDeprecated reports whether the doc comment contains a `Deprecated:`
+// paragraph.
+func isDeprecated(text string) bool {
+	_, notice := splitDeprecation(text)
+	return notice != ""
+}
+
+// IsDeprecated reports whether the provided doc comment marks its symbol as
+// deprecated.
+func (t TemplateUtils) IsDeprecated(text string) bool {
+	return isDeprecated(text)
+}
+
+// StrikeDeprecated strikes label through, in the markup of the configured
+// output format, if the provided doc comment marks its symbol as deprecated.
+func (t TemplateUtils) StrikeDeprecated(text, label string) string {
+	if !isDeprecated(text) {
+		return label
+	}
+	return t.renderer.Strike(label)
+}
+
+// DocToMD converts a symbol's doc comment into markdown. Unlike CommentToMD,
+// the `Deprecated:` paragraph is pulled out of the body and rendered as a
+// callout block following it.
+func (t TemplateUtils) DocToMD(text string) string {
+	body, notice := splitDeprecation(text)
+
+	var buf bytes.Buffer
+	t.toMD(&buf, body)
+	if notice == "" {
+		return buf.String()
+	}
+
+	if buf.Len() > 0 {
+		// separate the callout from the body by exactly one blank line
+		body := strings.TrimRight(buf.String(), "\n")
+		buf.Reset()
+		buf.WriteString(body + "\n\n")
+	}
+	var noticeBuf bytes.Buffer
+	t.toMD(&noticeBuf, notice)
+	if t.admonitions {
+		buf.WriteString("> [!WARNING]\n")
+	}
+	MarkdownRenderer{}.Deprecation(&buf, noticeBuf.String())
+
+	return buf.String()
+}
+
+// DocToDoc converts a symbol's doc comment into the markup of the configured
+// output format, with its `Deprecated:` paragraph rendered as a callout
+// following the body, as DocToMD does.
+func (t TemplateUtils) DocToDoc(text string) string {
+	body, notice := splitDeprecation(text)
+
+	var buf bytes.Buffer
+	RenderComment(&buf, body, t.renderer, t.anchors)
+	if notice == "" {
+		return buf.String()
+	}
+
+	var noticeBuf bytes.Buffer
+	RenderComment(&noticeBuf, notice, t.renderer, t.anchors)
+	t.renderer.Deprecation(&buf, noticeBuf.String())
+
+	return buf.String()
+}
+
+// filterDeprecated removes every deprecated symbol from the package
+// documentation held by info.
+func filterDeprecated(info *godoc.PageInfo) {
+	if info.PDoc == nil {
+		return
+	}
+
+	pkg := info.PDoc
+	pkg.Consts = filterDeprecatedValues(pkg.Consts)
+	pkg.Vars = filterDeprecatedValues(pkg.Vars)
+	pkg.Funcs = filterDeprecatedFuncs(pkg.Funcs)
+
+	types := pkg.Types[:0]
+	for _, typ := range pkg.Types {
+		if isDeprecated(typ.Doc) {
+			continue
+		}
+		typ.Consts = filterDeprecatedValues(typ.Consts)
+		typ.Vars = filterDeprecatedValues(typ.Vars)
+		typ.Funcs = filterDeprecatedFuncs(typ.Funcs)
+		typ.Methods = filterDeprecatedFuncs(typ.Methods)
+		types = append(types, typ)
+	}
+	pkg.Types = types
+}
+
+func filterDeprecatedValues(values []*doc.Value) []*doc.Value {
+	out := values[:0]
+	for _, v := range values {
+		if !isDeprecated(v.Doc) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func filterDeprecatedFuncs(funcs []*doc.Func) []*doc.Func {
+	out := funcs[:0]
+	for _, f := range funcs {
+		if !isDeprecated(f.Doc) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
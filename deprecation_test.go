@@ -0,0 +1,127 @@
+package godoc2md
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitDeprecation(t *testing.T) {
+	for _, tt := range []struct {
+		name, text   string
+		body, notice string
+	}{
+		{
+			name: "absent",
+			text: "F does things.\n\nIt does them well.\n",
+			body: "F does things.\n\nIt does them well.\n",
+		},
+		{
+			name:   "final paragraph",
+			text:   "F does things.\n\nDeprecated: Use G instead.\n",
+			body:   "F does things.",
+			notice: "Use G instead.",
+		},
+		{
+			name:   "mid-comment",
+			text:   "F does things.\n\nDeprecated: Use G instead.\n\nIt does them well.\n",
+			body:   "F does things.\n\nIt does them well.\n",
+			notice: "Use G instead.",
+		},
+		{
+			name:   "only paragraph",
+			text:   "Deprecated: Use G instead.\n",
+			notice: "Use G instead.",
+		},
+		{
+			name:   "multi-line notice",
+			text:   "F does things.\n\nDeprecated: Use G instead,\nwhich does them better.\n",
+			body:   "F does things.",
+			notice: "Use G instead,\nwhich does them better.",
+		},
+		{
+			name:   "first notice only",
+			text:   "Deprecated: Use G.\n\nDeprecated: Use H.\n",
+			body:   "Deprecated: Use H.\n",
+			notice: "Use G.",
+		},
+		{
+			name: "within a paragraph",
+			text: "F does things.\nDeprecated: not a notice.\n",
+			body: "F does things.\nDeprecated: not a notice.\n",
+		},
+		{
+			name: "lower case",
+			text: "F does things.\n\ndeprecated: not a notice.\n",
+			body: "F does things.\n\ndeprecated: not a notice.\n",
+		},
+	} {
+		body, notice := splitDeprecation(tt.text)
+		if body != tt.body || notice != tt.notice {
+			t.Errorf("%s: splitDeprecation = %q, %q, want %q, %q", tt.name, body, notice, tt.body, tt.notice)
+		}
+	}
+}
+
+func TestFilterDeprecated(t *testing.T) {
+	info := sourcePage(t, `// Package p does things.
+package p
+
+// Deprecated: Use New.
+const Old = 1
+
+// Kept is kept.
+const Kept = 1
+
+// Group is a group.
+//
+// Deprecated: The group is unused.
+var (
+	A = 1
+	B = 2
+)
+
+// F does things.
+//
+// Deprecated: Use G.
+func F() {}
+
+// G does things.
+func G() {}
+
+// T is a thing.
+type T int
+
+// NewT returns a T.
+//
+// Deprecated: Use MakeT.
+func NewT() T { return 0 }
+
+// MakeT returns a T.
+func MakeT() T { return 0 }
+
+// Deprecated: Use N.
+func (T) M() {}
+
+// N does things.
+func (T) N() {}
+
+// Deprecated: Use T.
+type U int
+
+// NewU returns a U.
+func NewU() U { return 0 }
+
+// V is deprecated mid-comment.
+//
+// Deprecated: Use T.
+//
+// It used to be useful.
+type V int
+`)
+	filterDeprecated(info)
+
+	want := []string{"Kept", "G", "T", "MakeT", "T.N"}
+	if got := symbolNames(info.PDoc); !reflect.DeepEqual(got, want) {
+		t.Errorf("filterDeprecated kept %q, want %q", got, want)
+	}
+}
@@ -11,30 +11,119 @@
 //	# Generate Package Readme
 //	$ godoc2md $PACKAGE > $GOPATH/src/$PACKAGE/README.md
 //
+//	# Start a custom template from the built-in one, and check it
+//	$ godoc2md template dump > custom.tmpl
+//	$ godoc2md template check custom.tmpl
+//
+//	# Report undocumented symbols, failing below 80% coverage
+//	$ godoc2md -minCoverage 80 coverage $PACKAGE
+//
+//	# Write the changelog of the exported API between two git revisions
+//	$ godoc2md diff v1.0.0 HEAD ./pkg > CHANGELOG.md
+//
+//	# Write a Hugo content section, with front matter and section pages
+//	$ godoc2md -out content/api -site hugo ./pkg/a ./pkg/b
+//
+//	# Document the Linux API of a package, noting what Windows lacks
+//	$ godoc2md -platforms linux/amd64,windows/amd64 $PACKAGE
+//
+//	# Document a released version of a package, from the module cache
+//	$ godoc2md golang.org/x/text/language@v0.14.0
+//
+//	# Document a package as of a release tag, without checking it out
+//	$ godoc2md -rev v1.2.0 ./pkg
+//
+//	# Rewrite the pages of the packages being edited as they change
+//	$ godoc2md -watch -out docs ./pkg/a ./pkg/b
+//
+//	# Write the pages of many packages, reusing those that did not change
+//	$ godoc2md -cache .cache/godoc2md -out docs ./pkg/a ./pkg/b
+//
+//	# Preview the pages as GitHub renders them, reloading on changes
+//	$ godoc2md serve ./pkg/a ./pkg/b
+//
 //	# See all Options
 //	$ godoc2md
-//  usage: godoc2md package [more-packages ...]
+//  usage: godoc2md package[@version] [more-packages ...]
+//         godoc2md template dump | check <path>
+//         godoc2md coverage package [more-packages ...]
+//         godoc2md diff <old> <new> package
+//         godoc2md serve package [more-packages ...]
+//  -admonitions
+//  		render callouts, such as deprecation notices, with GitHub [!WARNING] admonition syntax
 //  -basePrefix go.mod
 //  		path prefix of go files. If not set, cli will attempt to set it by checking go.mod, current directory, and the 1st position argument
+//  -cache string
+//  		directory caching the pages of multi-package runs, skipping the packages whose sources, template and flags are unchanged
+//  -checkLinks string
+//  		report internal links without a target in the output: warn, or error to fail
 //  -ex
 //  		show examples in command line mode
+//  -format string
+//  		output format, one of: adoc, html, json, md, rst (default "md")
+//  -forge string
+//  		forge whose heading slugs anchors follow, one of: bitbucket, github, gitlab (default "github")
+//  -goarch string
+//  		target architecture to load packages for. Defaults to GOARCH
+//  -goos string
+//  		target operating system to load packages for. Defaults to GOOS
 //  -goroot GOROOT
 //  		directory of Go Root. Will attempt to lookup from GOROOT
 //  -hashformat string
 //  		source link URL hash format (default "#L%d")
+//  -hideDeprecated
+//  		omit symbols marked as deprecated
+//  -http string
+//  		loopback address the serve subcommand listens on (default "localhost:6060")
 //  -links
 //  		link identifiers to their declarations (default true)
+//  -model
+//  		execute the template with the stable godoc2md.Package model instead of godoc.PageInfo. Requires a -template replacing the built-in template, as its blocks are executed with godoc.PageInfo
+//  -minCoverage float
+//  		percentage of documented symbols below which the coverage subcommand fails
+//  -modzip string
+//  		module zip file to read the packages from, at the version it holds, instead of the module cache used for package@version arguments
+//  -notes string
+//  		regular expression matching the note markers (BUG, TODO, etc.) to render (default "BUG")
+//  -order string
+//  		order of the symbols: name, source, or source grouped under a heading per file, or per "Section: Title" comment (default "name")
+//  -out string
+//  		directory to write one page per package into. If set, every positional argument is documented as a package
+//  -parallel int
+//  		number of packages a multi-package run renders concurrently. Defaults to the number of CPUs
+//  -platforms string
+//  		comma separated list of goos/goarch platforms to load packages for, documenting the symbols of all of them and noting those missing from some. Symbols are documented as declared on the first platform they exist on
 //  -play
 //  		enable playground in web interface (default true)
+//  -rev string
+//  		git revision, such as a tag or a commit, to read the packages of the repository at instead of the working tree, pinning source links to its commit
+//  -site string
+//  		static site generator preset adding front matter and navigation files to the -out pages, one of: docusaurus, hugo, jekyll, mkdocs
 //  -sourceID string
 //  		URL for generated URLs. (default "master")
+//  -split
+//  		with -out, write every exported type, and the package level functions, to a page of its own next to the package page
 //  -tabwidth int
 //  		tab width (default 4)
+//  -tags string
+//  		comma separated list of build tags to consider satisfied when loading packages
 //  -template string
-//  		path to an alternate template file
+//  		path to a template file, or directory of template files, overriding blocks of the built-in template. Only the md template has blocks, those of other formats must be replaced entirely
 //  -timestamps
 //  		show timestamps with directory listings (default true)
 //  -urlPrefix string
 //  		URL for generated URLs.
 // -v	verbose mode
+//  -watch
+//  		with -out, keep running and rewrite the pages of the packages whose .go files, or template, change
+//
+// Inside a go.work workspace, the packages of all of its modules can be
+// documented in one run, named by import path or directory, and link to their
+// sources below the directory of their module.
+//
+// Custom templates can use the funcs of the godoc FuncMap and of
+// TemplateUtils.Methods, including the general purpose helpers synopsis,
+// upper, lower, title, camel, snake, join, default, indent, replace, contains,
+// list, dict, env, relpath and anchor. See the TemplateUtils methods of the
+// same names for their arguments.
 package godoc2md
@@ -0,0 +1,54 @@
+package godoc2md
+
+import (
+	"go/ast"
+	"go/doc"
+)
+
+// IsEnum reports whether the provided type follows the enum pattern: a named
+// type whose underlying type is not a composite (struct, interface, etc.) and
+// that has at least one block of constants declared with that type.
+//
+// go/doc already associates typed constants with their type, so the check
+// only has to look at the type's own declaration.
+func (t TemplateUtils) IsEnum(typ *doc.Type) bool {
+	if typ == nil || len(typ.Consts) == 0 || typ.Decl == nil {
+		return false
+	}
+
+	for _, spec := range typ.Decl.Specs {
+		ts, ok := spec.(*ast.TypeSpec)
+		if !ok || ts.Name.Name != typ.Name {
+			continue
+		}
+		switch ts.Type.(type) {
+		case *ast.Ident, *ast.SelectorExpr:
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasStringer reports whether the provided type declares a `String() string`
+// method, making its values printable by name.
+func (t TemplateUtils) HasStringer(typ *doc.Type) bool {
+	if typ == nil {
+		return false
+	}
+
+	for _, m := range typ.Methods {
+		if m.Name != "String" || m.Decl == nil {
+			continue
+		}
+		ft := m.Decl.Type
+		if ft.Params.NumFields() != 0 || ft.Results.NumFields() != 1 {
+			continue
+		}
+		if id, ok := ft.Results.List[0].Type.(*ast.Ident); ok && id.Name == "string" {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,67 @@
+package godoc2md
+
+import (
+	"bytes"
+	"go/doc"
+	"go/printer"
+	"strings"
+
+	"golang.org/x/tools/godoc"
+)
+
+// exampleCode trims the printed code of an example function down to its body,
+// without the `// Output:` comment if output is set.
+func exampleCode(code, output string) string {
+	code = strings.TrimRight(code, " \n")
+	if n := len(code); n >= 2 && code[0] == '{' && code[n-1] == '}' {
+		// remove surrounding braces, and the indentation they add
+		lines := strings.Split(strings.Trim(code[1:n-1], "\n"), "\n")
+		unindent(lines)
+		code = strings.Join(lines, "\n")
+		if i := strings.LastIndex(code, "// Output:"); i >= 0 && output != "" {
+			code = strings.TrimSpace(code[:i])
+		}
+	}
+	return code
+}
+
+// ExampleToMD renders the examples of the symbol called name, where methods
+// are named Type_Method and the package itself is named "", as Markdown. Each
+// example is anchored at "example_" followed by its full name, as linked from
+// the index. Examples are only rendered with the `-ex` flag.
+func (t TemplateUtils) ExampleToMD(info *godoc.PageInfo, name string) string {
+	if !t.showExamples {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	for _, eg := range info.Examples {
+		if egName, _ := splitExampleName(eg.Name); egName == name {
+			t.writeExample(&buf, info, eg)
+		}
+	}
+	return buf.String()
+}
+
+func (t TemplateUtils) writeExample(buf *bytes.Buffer, info *godoc.PageInfo, eg *doc.Example) {
+	var code bytes.Buffer
+	config := printer.Config{Mode: printer.UseSpaces, Tabwidth: t.tabWidth}
+	if err := config.Fprint(&code, info.FSet, &printer.CommentedNode{Node: eg.Code, Comments: eg.Comments}); err != nil {
+		return
+	}
+
+	title := "Example"
+	if _, suffix := splitExampleName(eg.Name); suffix != "" {
+		title += " (" + suffix + ")"
+	}
+	buf.WriteString(`**<a name="example_` + eg.Name + `">` + title + "</a>**\n\n")
+	if eg.Doc != "" {
+		t.toMD(buf, eg.Doc)
+		buf.WriteString("\n")
+	}
+	buf.WriteString(t.MDEscapeGo(exampleCode(code.String(), eg.Output)))
+	buf.WriteString("\n")
+	if eg.Output != "" {
+		buf.WriteString("Output:\n\n```\n" + strings.TrimRight(eg.Output, "\n") + "\n```\n\n")
+	}
+}
@@ -0,0 +1,78 @@
+package godoc2md
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Format describes one of the built-in output formats.
+type Format struct {
+	// Name is the value accepted by the `-format` flag.
+	Name string
+	// Filename is the name of the page written for each package when the
+	// output is written into a directory.
+	Filename string
+	// Template is the built-in package template of the format.
+	Template string
+	// Blocks is set if Template is split into the blocks of pkgTemplate,
+	// which `-template` can override one by one. The templates of other
+	// formats can only be replaced entirely.
+	Blocks bool
+	// Renderer converts doc comments into the markup of the format.
+	Renderer Renderer
+	// JSON formats encode the Package model instead of executing a template.
+	JSON bool
+}
+
+var formats = map[string]Format{
+	"md": {
+		Name:     "md",
+		Filename: "README.md",
+		Template: pkgTemplate,
+		Blocks:   true,
+		Renderer: MarkdownRenderer{},
+	},
+	"html": {
+		Name:     "html",
+		Filename: "index.html",
+		Template: htmlTemplate,
+		Renderer: HTMLRenderer{},
+	},
+	"adoc": {
+		Name:     "adoc",
+		Filename: "index.adoc",
+		Template: adocTemplate,
+		Renderer: AsciiDocRenderer{},
+	},
+	"rst": {
+		Name:     "rst",
+		Filename: "index.rst",
+		Template: rstTemplate,
+		Renderer: RSTRenderer{},
+	},
+	"json": {
+		Name:     "json",
+		Filename: "index.json",
+		Renderer: MarkdownRenderer{},
+		JSON:     true,
+	},
+}
+
+// GetFormat returns the built-in output format registered under name.
+func GetFormat(name string) (Format, error) {
+	format, ok := formats[name]
+	if !ok {
+		return Format{}, fmt.Errorf("unknown format %q, must be one of: %s", name, strings.Join(formatNames(), ", "))
+	}
+	return format, nil
+}
+
+func formatNames() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
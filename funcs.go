@@ -4,10 +4,14 @@ import (
 	"bytes"
 	"fmt"
 	"go/ast"
+	"go/doc"
+	"go/token"
+	"io"
 	"net/url"
 	"path"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"golang.org/x/tools/godoc"
 )
@@ -31,17 +35,79 @@ type TemplateUtils struct {
 	urlPrefix         string
 	timeFormat        string
 	srcLinkHashFormat string
+	admonitions       bool
+	packages          []string
+	pageFilename      string
+	renderer          Renderer
+	forge             Forge
+	anchors           *Anchors
+	tabWidth          int
+	showExamples      bool
+	// pagePath is the path of the page being rendered, relative to the
+	// output directory.
+	pagePath string
+	// page is the name of the page being rendered, and split maps the
+	// anchors of the split layout to their pages, if enabled.
+	page  string
+	split map[string]string
+	order string
+	// module is the workspace module of the package being rendered, whose
+	// import path is importPath, if any.
+	module     *Module
+	importPath string
+}
+
+// Symbol is the data the template blocks documenting a single type, function
+// or method are executed with.
+type Symbol struct {
+	// Page is the package documentation the symbol belongs to.
+	Page *godoc.PageInfo
+	// Type is the documented type, or the type a function or method is
+	// associated with. It is nil for package level functions.
+	Type *doc.Type
+	// Func is the documented function or method, if any.
+	Func *doc.Func
+	// Heading is the Markdown heading prefix of the symbol, e.g. "##".
+	Heading string
 }
 
 // NewTemplateUtils returns a new TemplateUtils object configured from the
 // provided CLI instance.
 func NewTemplateUtils(cfg *Cli) TemplateUtils {
+	format, err := GetFormat(*cfg.Format)
+	if err != nil {
+		format = formats["md"]
+	}
+	filename, err := pageFilename(cfg)
+	if err != nil {
+		filename = format.Filename
+	}
+	forge, err := GetForge(*cfg.Forge)
+	if err != nil {
+		forge = ForgeGitHub
+	}
+
+	packages := make([]string, len(cfg.Packages))
+	for i, arg := range cfg.Packages {
+		packages[i] = argImportPath(cfg, arg)
+	}
+
 	return TemplateUtils{
 		sourceID:          *cfg.SourceID,
 		basePrefix:        *cfg.BasePrefix,
 		urlPrefix:         *cfg.UrlPrefix,
 		srcLinkHashFormat: *cfg.SrcLinkHashFormat,
 		timeFormat:        TimeFormat,
+		admonitions:       *cfg.Admonitions,
+		packages:          packages,
+		pageFilename:      filename,
+		pagePath:          filename,
+		renderer:          format.Renderer,
+		forge:             forge,
+		anchors:           NewAnchors(forge),
+		tabWidth:          *cfg.TabWidth,
+		showExamples:      *cfg.ShowExamples,
+		order:             *cfg.Order,
 	}
 }
 
@@ -49,31 +115,154 @@ func NewTemplateUtils(cfg *Cli) TemplateUtils {
 // provided to the presenter and the keys are made available as functions to the
 // template.
 func (t TemplateUtils) Methods() map[string]interface{} {
-	return map[string]interface{}{
-		"comment_md":   t.CommentToMD,
-		"srcfile_url":  t.GetSourceFileURL,
-		"base":         t.StripBasePrefix,
-		"md":           t.MDEscapeInline,
-		"goCode":       t.MDEscapeGo,
-		"kebab":        t.kebabFunc,
-		"bitscape":     t.bitscapeFunc, //Escape [] for bitbucket confusion
-		"trim_prefix":  strings.TrimPrefix,
-		"last_item":    t.isLastItem,
-		"current_time": t.GetCurrentTime,
-		"get_full_url": t.GetFullURL,
+	methods := map[string]interface{}{
+		"comment_md":        t.CommentToMD,
+		"srcfile_url":       t.GetSourceFileURL,
+		"base":              t.StripBasePrefix,
+		"md":                t.MDEscapeInline,
+		"goCode":            t.MDEscapeGo,
+		"kebab":             t.kebabFunc,
+		"bitscape":          t.bitscapeFunc, //Escape [] for bitbucket confusion
+		"trim_prefix":       strings.TrimPrefix,
+		"last_item":         t.isLastItem,
+		"current_time":      t.GetCurrentTime,
+		"get_full_url":      t.GetFullURL,
+		"note_url":          t.GetNoteURL,
+		"note_md":           t.NoteToMD,
+		"is_enum":           t.IsEnum,
+		"has_stringer":      t.HasStringer,
+		"doc_md":            t.DocToMD,
+		"deprecated":        t.IsDeprecated,
+		"strike_deprecated": t.StrikeDeprecated,
+		"platforms":         t.PlatformsNote,
+		"highlight_go":      t.HighlightGo,
+		"site_pages":        t.SitePages,
+		"page_link":         t.PageLink,
+		"comment":           t.CommentToDoc,
+		"doc":               t.DocToDoc,
+		"escape":            t.Escape,
+		"go_block":          t.GoBlock,
+		"underline":         t.Underline,
+		"oneline":           t.OneLine,
+		"symbol":            t.NewSymbol,
+		"symbol_anchor":     SymbolAnchor,
+		"example_md":        t.ExampleToMD,
+		"show_examples":     t.ShowExamples,
+		"anchor_link":       t.AnchorLink,
+		"symbol_link":       t.SymbolLink,
+		"split":             t.IsSplit,
+		"symbol_groups":     t.SymbolGroups,
 	}
+
+	for name, fn := range t.helperFuncs() {
+		methods[name] = fn
+	}
+
+	return methods
+}
+
+// NewSymbol returns the Symbol passed to the "type", "func" and "method"
+// template blocks.
+func (t TemplateUtils) NewSymbol(page *godoc.PageInfo, typ *doc.Type, fn *doc.Func, heading string) Symbol {
+	return Symbol{Page: page, Type: typ, Func: fn, Heading: heading}
 }
 
 // CommentToMD converts the provided text, from Go source comment, into markdown.
 func (t TemplateUtils) CommentToMD(comment string) string {
 	var buf bytes.Buffer
-	ToMD(&buf, comment)
+	t.toMD(&buf, comment)
+	return buf.String()
+}
+
+// PlatformsNote returns the note of the platforms symbol, a *doc.Value,
+// *doc.Type or *doc.Func of page, is available on, as a paragraph in the markup
+// of the configured output format. It is empty unless the symbol is missing
+// from some of the platforms of the `-platforms` flag.
+func (t TemplateUtils) PlatformsNote(page *godoc.PageInfo, symbol interface{}) string {
+	if page.PDoc == nil {
+		return ""
+	}
+	note := symbolPlatforms(page.PDoc, symbol)
+	if note == "" {
+		return ""
+	}
+	return t.CommentToDoc(note)
+}
+
+// ShowExamples reports whether examples are rendered, as set by the `-ex`
+// flag.
+func (t TemplateUtils) ShowExamples() bool {
+	return t.showExamples
+}
+
+// toMD converts comment text to Markdown like ToMD, taking heading anchors
+// from the anchors of the page.
+func (t TemplateUtils) toMD(w io.Writer, text string) {
+	RenderComment(w, text, MarkdownRenderer{}, t.anchors)
+}
+
+// CommentToDoc converts the provided text, from Go source comment, into the
+// markup of the configured output format.
+func (t TemplateUtils) CommentToDoc(comment string) string {
+	var buf bytes.Buffer
+	RenderComment(&buf, comment, t.renderer, t.anchors)
+	return buf.String()
+}
+
+// Escape escapes the characters of text that are meaningful to the markup of
+// the configured output format.
+func (t TemplateUtils) Escape(text string) string {
+	return t.renderer.Escape(text)
+}
+
+// GoBlock fences a string of text as Go code, in the markup of the configured
+// output format.
+func (t TemplateUtils) GoBlock(text string) string {
+	var buf bytes.Buffer
+	lines := strings.SplitAfter(strings.TrimRight(text, " \n"), "\n")
+	t.renderer.CodeBlock(&buf, "go", lines)
 	return buf.String()
 }
 
+// Underline returns a line of the character c as long as title, as used to
+// mark up section headings in reStructuredText.
+func (t TemplateUtils) Underline(c, title string) string {
+	return strings.Repeat(c, utf8.RuneCountInString(title))
+}
+
+// OneLine collapses all runs of whitespace in text, including line breaks,
+// into single spaces.
+func (t TemplateUtils) OneLine(text string) string {
+	return oneLine(text)
+}
+
+func oneLine(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
 // GetFullURL returns the URL, including line number, of the provided source
 // code declaration.
 func (t TemplateUtils) GetFullURL(pkg *godoc.PageInfo, decl ast.Decl) string {
+	return t.getPosURL(pkg, decl.Pos())
+}
+
+// GetNoteURL returns the URL, including line number, of the provided note
+// (BUG, TODO, etc.).
+func (t TemplateUtils) GetNoteURL(pkg *godoc.PageInfo, note *doc.Note) string {
+	return t.getPosURL(pkg, note.Pos)
+}
+
+// NoteToMD converts the body of a note into markdown that can be nested under
+// a list item.
+func (t TemplateUtils) NoteToMD(note *doc.Note) string {
+	var buf bytes.Buffer
+	t.toMD(&buf, note.Body)
+
+	md := strings.TrimRight(buf.String(), "\n")
+	return strings.Replace(md, "\n", "\n  ", -1)
+}
+
+func (t TemplateUtils) getPosURL(pkg *godoc.PageInfo, pos token.Pos) string {
 	sourceURL, err := url.Parse(t.urlPrefix)
 	if err != nil {
 		return fmt.Sprintf("%v", err)
@@ -88,10 +277,10 @@ func (t TemplateUtils) GetFullURL(pkg *godoc.PageInfo, decl ast.Decl) string {
 
 	// Gather the fragments of the intended file path/location.
 	pathFragments := []string{t.getPathPrefix()}
-	pathFragments = append(pathFragments, t.StripBasePrefix(pkg.PDoc.ImportPath))
+	pathFragments = append(pathFragments, t.packageDir(pkg.PDoc.ImportPath))
 
 	// find source file/position and generate string.
-	sourceLoc := pkg.FSet.Position(decl.Pos())
+	sourceLoc := pkg.FSet.Position(pos)
 	raw, err := url.Parse(fmt.Sprintf(t.srcLinkHashFormat, sourceLoc.Line))
 	if err != nil {
 		return fmt.Sprintf("%v", err)
@@ -125,6 +314,9 @@ func (t TemplateUtils) GetSourceFileURL(s string) string {
 
 	repoPath := t.StripBasePrefix(sourceURL.Path)
 	filename := path.Clean("/" + strings.TrimPrefix(repoPath, "/target"))
+	if t.module != nil {
+		filename = path.Join(t.packageDir(t.importPath), path.Base(filename))
+	}
 	sourceURL.Path = path.Join(t.getPathPrefix(), filename)
 
 	return sourceURL.String()
@@ -153,6 +345,9 @@ func (t TemplateUtils) GetCurrentTime() string {
 	return time.Now().UTC().Format(t.timeFormat)
 }
 
+// kebabFunc is kept for existing custom templates.
+//
+// Deprecated: Use anchor, which slugs headings the way the forge does.
 func (t TemplateUtils) kebabFunc(text string) string {
 	s := strings.Replace(strings.ToLower(text), " ", "-", -1)
 	s = strings.Replace(s, ".", "-", -1)
@@ -168,10 +363,22 @@ func (t TemplateUtils) bitscapeFunc(text string) string {
 
 func (t TemplateUtils) getPathPrefix() string {
 	branchPath := fmt.Sprintf("blob/%s", t.sourceID)
+	if t.module != nil {
+		return path.Join(t.module.repoPrefix(), branchPath, t.module.RepoDir)
+	}
 
 	return path.Join(t.basePrefix, branchPath)
 }
 
+// packageDir returns the directory of the package at importPath, relative to
+// its module, or to the basePrefix outside of workspaces.
+func (t TemplateUtils) packageDir(importPath string) string {
+	if t.module != nil {
+		return strings.TrimPrefix(importPath, t.module.Path)
+	}
+	return t.StripBasePrefix(importPath)
+}
+
 func (t TemplateUtils) isLastItem(idx int, list []string) bool {
 	return idx+1 >= len(list)
 }
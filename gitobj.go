@@ -0,0 +1,574 @@
+package godoc2md
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// The types of the objects of a git object store.
+const (
+	gitCommit = "commit"
+	gitTree   = "tree"
+	gitBlob   = "blob"
+	gitTag    = "tag"
+)
+
+// gitPackTypes are the object types of packfile entries, by their number.
+// Numbers 6 and 7 are the offset and reference deltas.
+var gitPackTypes = map[byte]string{1: gitCommit, 2: gitTree, 3: gitBlob, 4: gitTag}
+
+const (
+	gitOfsDelta = 6
+	gitRefDelta = 7
+)
+
+// gitHash is the SHA-1 name of a git object.
+type gitHash [20]byte
+
+func (h gitHash) String() string {
+	return hex.EncodeToString(h[:])
+}
+
+func parseGitHash(s string) (gitHash, bool) {
+	var h gitHash
+	if len(s) != 2*len(h) {
+		return h, false
+	}
+	if _, err := hex.Decode(h[:], []byte(s)); err != nil {
+		return h, false
+	}
+	return h, true
+}
+
+// gitObject is an object of the store, inflated and undeltified.
+type gitObject struct {
+	typ  string
+	data []byte
+}
+
+// gitRepo reads the objects and refs of a git repository straight from its
+// .git directory: loose objects, packfiles and their version 2 indexes, loose
+// refs and packed-refs. Nothing is ever written.
+type gitRepo struct {
+	// gitDir holds HEAD, and commonDir the objects and refs, which only
+	// differ for linked worktrees.
+	gitDir    string
+	commonDir string
+
+	// mu guards the caches of the objects read, and of the packs.
+	mu    sync.Mutex
+	packs []*gitPack
+	cache map[gitHash]gitObject
+}
+
+// openGitRepo opens the repository whose working tree is rooted at root.
+func openGitRepo(root string) (*gitRepo, error) {
+	gitDir := filepath.Join(root, ".git")
+	fi, err := os.Stat(gitDir)
+	if err != nil {
+		return nil, fmt.Errorf("%s: not a git repository", root)
+	}
+	if !fi.IsDir() {
+		// linked worktrees and submodules have a "gitdir: path" file
+		data, err := os.ReadFile(gitDir)
+		if err != nil {
+			return nil, err
+		}
+		dir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+		if !ok {
+			return nil, fmt.Errorf("%s: invalid .git file", root)
+		}
+		gitDir = strings.TrimSpace(dir)
+		if !filepath.IsAbs(gitDir) {
+			gitDir = filepath.Join(root, gitDir)
+		}
+	}
+
+	r := &gitRepo{gitDir: gitDir, commonDir: gitDir, cache: map[gitHash]gitObject{}}
+	if data, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		r.commonDir = strings.TrimSpace(string(data))
+		if !filepath.IsAbs(r.commonDir) {
+			r.commonDir = filepath.Join(gitDir, r.commonDir)
+		}
+	}
+
+	idxs, err := filepath.Glob(filepath.Join(r.commonDir, "objects", "pack", "*.idx"))
+	if err != nil {
+		return nil, err
+	}
+	for _, idx := range idxs {
+		pack, err := openGitPack(idx)
+		if err != nil {
+			return nil, err
+		}
+		r.packs = append(r.packs, pack)
+	}
+	return r, nil
+}
+
+// resolve returns the commit named by rev: a full or abbreviated commit hash,
+// or a ref name as accepted by git, such as a branch or a tag. Annotated tags
+// are peeled to their commit.
+func (r *gitRepo) resolve(rev string) (gitHash, error) {
+	h, ok := parseGitHash(rev)
+	if !ok {
+		h, ok = r.ref(rev)
+	}
+	if !ok {
+		var err error
+		if h, ok, err = r.abbrev(rev); err != nil {
+			return h, err
+		}
+	}
+	if !ok {
+		return h, fmt.Errorf("unknown revision %s", rev)
+	}
+
+	for {
+		obj, err := r.object(h)
+		if err != nil {
+			return h, err
+		}
+		switch obj.typ {
+		case gitCommit:
+			return h, nil
+		case gitTag:
+			if h, ok = gitHeader(obj.data, "object"); !ok {
+				return h, fmt.Errorf("%s: invalid tag %s", rev, h)
+			}
+		default:
+			return h, fmt.Errorf("%s: %s is a %s, not a commit", rev, h, obj.typ)
+		}
+	}
+}
+
+// ref looks rev up in the refs of the repository, in the order of
+// gitrevisions(7).
+func (r *gitRepo) ref(rev string) (gitHash, bool) {
+	for _, name := range []string{rev, "refs/" + rev, "refs/tags/" + rev, "refs/heads/" + rev, "refs/remotes/" + rev, "refs/remotes/" + rev + "/HEAD"} {
+		if h, ok := r.readRef(name, 0); ok {
+			return h, true
+		}
+	}
+	return gitHash{}, false
+}
+
+// readRef returns the object the ref name points to, following symbolic refs.
+func (r *gitRepo) readRef(name string, depth int) (gitHash, bool) {
+	if depth > 5 {
+		return gitHash{}, false
+	}
+
+	dir := r.commonDir
+	if !strings.Contains(name, "/") {
+		// HEAD and the like are per worktree
+		dir = r.gitDir
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name))); err == nil {
+		content := strings.TrimSpace(string(data))
+		if target, ok := strings.CutPrefix(content, "ref:"); ok {
+			return r.readRef(strings.TrimSpace(target), depth+1)
+		}
+		return parseGitHash(content)
+	}
+
+	data, err := os.ReadFile(filepath.Join(r.commonDir, "packed-refs"))
+	if err != nil {
+		return gitHash{}, false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if hash, ref, ok := strings.Cut(line, " "); ok && ref == name {
+			return parseGitHash(hash)
+		}
+	}
+	return gitHash{}, false
+}
+
+// abbrev returns the object whose hash starts with the hexadecimal prefix,
+// which must be unambiguous.
+func (r *gitRepo) abbrev(prefix string) (gitHash, bool, error) {
+	if len(prefix) < 4 || len(prefix) >= 40 || strings.Trim(strings.ToLower(prefix), "0123456789abcdef") != "" {
+		return gitHash{}, false, nil
+	}
+	prefix = strings.ToLower(prefix)
+
+	found := map[gitHash]bool{}
+	names, _ := filepath.Glob(filepath.Join(r.commonDir, "objects", prefix[:2], prefix[2:]+"*"))
+	for _, name := range names {
+		if h, ok := parseGitHash(prefix[:2] + filepath.Base(name)); ok {
+			found[h] = true
+		}
+	}
+	for _, pack := range r.packs {
+		for _, h := range pack.withPrefix(prefix) {
+			found[h] = true
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return gitHash{}, false, nil
+	case 1:
+		for h := range found {
+			return h, true, nil
+		}
+	}
+	return gitHash{}, false, fmt.Errorf("ambiguous revision %s", prefix)
+}
+
+// object reads the object h, loose or packed.
+func (r *gitRepo) object(h gitHash) (gitObject, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.readObject(h)
+}
+
+// readObject reads the object h, with mu held.
+func (r *gitRepo) readObject(h gitHash) (gitObject, error) {
+	if obj, ok := r.cache[h]; ok {
+		return obj, nil
+	}
+
+	obj, err := r.looseObject(h)
+	if os.IsNotExist(err) {
+		err = fmt.Errorf("object %s not found", h)
+		for _, pack := range r.packs {
+			if offset, ok := pack.offset(h); ok {
+				obj, err = pack.object(r, offset)
+				break
+			}
+		}
+	}
+	if err != nil {
+		return gitObject{}, err
+	}
+
+	r.cache[h] = obj
+	return obj, nil
+}
+
+func (r *gitRepo) looseObject(h gitHash) (gitObject, error) {
+	s := h.String()
+	f, err := os.Open(filepath.Join(r.commonDir, "objects", s[:2], s[2:]))
+	if err != nil {
+		return gitObject{}, err
+	}
+	defer f.Close()
+
+	zr, err := zlib.NewReader(f)
+	if err != nil {
+		return gitObject{}, fmt.Errorf("object %s: %v", h, err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return gitObject{}, fmt.Errorf("object %s: %v", h, err)
+	}
+
+	// the content follows a "type size\x00" header
+	header, content, ok := bytes.Cut(data, []byte{0})
+	typ, _, _ := strings.Cut(string(header), " ")
+	if !ok {
+		return gitObject{}, fmt.Errorf("object %s: invalid header", h)
+	}
+	return gitObject{typ: typ, data: content}, nil
+}
+
+// commitTree returns the root tree of the commit h.
+func (r *gitRepo) commitTree(h gitHash) (gitHash, error) {
+	obj, err := r.object(h)
+	if err != nil {
+		return h, err
+	}
+	tree, ok := gitHeader(obj.data, "tree")
+	if obj.typ != gitCommit || !ok {
+		return h, fmt.Errorf("%s: not a commit", h)
+	}
+	return tree, nil
+}
+
+// gitHeader returns the object named by the key header line of the commit or
+// tag data.
+func gitHeader(data []byte, key string) (gitHash, bool) {
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" {
+			break
+		}
+		if value, ok := strings.CutPrefix(line, key+" "); ok {
+			return parseGitHash(value)
+		}
+	}
+	return gitHash{}, false
+}
+
+// gitTreeEntry is an entry of a tree object.
+type gitTreeEntry struct {
+	name string
+	mode uint32
+	hash gitHash
+}
+
+// The file modes of tree entries.
+const (
+	gitModeTree    = 0o40000
+	gitModeSymlink = 0o120000
+	gitModeGitlink = 0o160000
+)
+
+func (e gitTreeEntry) isTree() bool {
+	return e.mode == gitModeTree
+}
+
+// tree reads the entries of the tree object h.
+func (r *gitRepo) tree(h gitHash) ([]gitTreeEntry, error) {
+	obj, err := r.object(h)
+	if err != nil {
+		return nil, err
+	}
+	if obj.typ != gitTree {
+		return nil, fmt.Errorf("%s: not a tree", h)
+	}
+
+	// entries are "mode name\x00" followed by the raw hash
+	var entries []gitTreeEntry
+	for data := obj.data; len(data) > 0; {
+		header, rest, ok := bytes.Cut(data, []byte{0})
+		if !ok || len(rest) < len(gitHash{}) {
+			return nil, fmt.Errorf("tree %s: invalid entry", h)
+		}
+		mode, name, _ := strings.Cut(string(header), " ")
+		m, err := strconv.ParseUint(mode, 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("tree %s: invalid mode %q", h, mode)
+		}
+		e := gitTreeEntry{name: name, mode: uint32(m)}
+		copy(e.hash[:], rest)
+		entries = append(entries, e)
+		data = rest[len(e.hash):]
+	}
+	return entries, nil
+}
+
+// gitPack is a packfile and its index.
+type gitPack struct {
+	filename string
+	// hashes are sorted, as in the index, and offsets are their positions
+	// in the packfile.
+	hashes  []gitHash
+	offsets []int64
+	// deltaBases caches the objects delta entries are based on, by offset.
+	deltaBases map[int64]gitObject
+}
+
+// openGitPack reads the version 2 pack index idx, of the packfile next to it.
+func openGitPack(idx string) (*gitPack, error) {
+	data, err := os.ReadFile(idx)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 8+256*4 || !bytes.Equal(data[:4], []byte("\377tOc")) || binary.BigEndian.Uint32(data[4:]) != 2 {
+		return nil, fmt.Errorf("%s: unsupported pack index", idx)
+	}
+
+	n := int(binary.BigEndian.Uint32(data[8+255*4:]))
+	hashesAt := 8 + 256*4
+	offsetsAt := hashesAt + n*20 + n*4
+	largeAt := offsetsAt + n*4
+	if len(data) < largeAt {
+		return nil, fmt.Errorf("%s: truncated pack index", idx)
+	}
+
+	p := &gitPack{
+		filename:   strings.TrimSuffix(idx, ".idx") + ".pack",
+		hashes:     make([]gitHash, n),
+		offsets:    make([]int64, n),
+		deltaBases: map[int64]gitObject{},
+	}
+	for i := 0; i < n; i++ {
+		copy(p.hashes[i][:], data[hashesAt+i*20:])
+		offset := binary.BigEndian.Uint32(data[offsetsAt+i*4:])
+		if offset&0x80000000 == 0 {
+			p.offsets[i] = int64(offset)
+			continue
+		}
+		// large offsets are in a table of 8 byte ones
+		at := largeAt + int(offset&0x7fffffff)*8
+		if len(data) < at+8 {
+			return nil, fmt.Errorf("%s: truncated pack index", idx)
+		}
+		p.offsets[i] = int64(binary.BigEndian.Uint64(data[at:]))
+	}
+	return p, nil
+}
+
+func (p *gitPack) offset(h gitHash) (int64, bool) {
+	i := sort.Search(len(p.hashes), func(i int) bool {
+		return bytes.Compare(p.hashes[i][:], h[:]) >= 0
+	})
+	if i < len(p.hashes) && p.hashes[i] == h {
+		return p.offsets[i], true
+	}
+	return 0, false
+}
+
+// withPrefix returns the hashes of the pack starting with the hexadecimal
+// prefix.
+func (p *gitPack) withPrefix(prefix string) []gitHash {
+	i := sort.Search(len(p.hashes), func(i int) bool {
+		return p.hashes[i].String() >= prefix
+	})
+	var found []gitHash
+	for ; i < len(p.hashes) && strings.HasPrefix(p.hashes[i].String(), prefix); i++ {
+		found = append(found, p.hashes[i])
+	}
+	return found
+}
+
+// object reads the entry of the packfile at offset, applying its deltas,
+// with the mutex of r held. Reference deltas are based on objects read from r.
+func (p *gitPack) object(r *gitRepo, offset int64) (gitObject, error) {
+	if obj, ok := p.deltaBases[offset]; ok {
+		return obj, nil
+	}
+
+	f, err := os.Open(p.filename)
+	if err != nil {
+		return gitObject{}, err
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(io.NewSectionReader(f, offset, 1<<62))
+	c, err := br.ReadByte()
+	if err != nil {
+		return gitObject{}, err
+	}
+	// the header is the type and the size, in a variable length encoding
+	typ := (c >> 4) & 7
+	for c&0x80 != 0 {
+		if c, err = br.ReadByte(); err != nil {
+			return gitObject{}, err
+		}
+	}
+
+	var base gitObject
+	switch typ {
+	case gitOfsDelta:
+		c, err := br.ReadByte()
+		if err != nil {
+			return gitObject{}, err
+		}
+		rel := int64(c & 0x7f)
+		for c&0x80 != 0 {
+			if c, err = br.ReadByte(); err != nil {
+				return gitObject{}, err
+			}
+			rel = (rel+1)<<7 | int64(c&0x7f)
+		}
+		if base, err = p.object(r, offset-rel); err != nil {
+			return gitObject{}, err
+		}
+		p.deltaBases[offset-rel] = base
+	case gitRefDelta:
+		var h gitHash
+		if _, err := io.ReadFull(br, h[:]); err != nil {
+			return gitObject{}, err
+		}
+		if base, err = r.readObject(h); err != nil {
+			return gitObject{}, err
+		}
+	default:
+		if gitPackTypes[typ] == "" {
+			return gitObject{}, fmt.Errorf("%s: invalid object type %d at %d", p.filename, typ, offset)
+		}
+	}
+
+	zr, err := zlib.NewReader(br)
+	if err != nil {
+		return gitObject{}, fmt.Errorf("%s: object at %d: %v", p.filename, offset, err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return gitObject{}, fmt.Errorf("%s: object at %d: %v", p.filename, offset, err)
+	}
+
+	if typ != gitOfsDelta && typ != gitRefDelta {
+		return gitObject{typ: gitPackTypes[typ], data: data}, nil
+	}
+	data, err = applyGitDelta(base.data, data)
+	if err != nil {
+		return gitObject{}, fmt.Errorf("%s: object at %d: %v", p.filename, offset, err)
+	}
+	return gitObject{typ: base.typ, data: data}, nil
+}
+
+// applyGitDelta returns the object delta reconstructs from base: the sizes of
+// both, followed by instructions copying ranges of base or inserting data.
+func applyGitDelta(base, delta []byte) ([]byte, error) {
+	size := func() int {
+		n, shift := 0, 0
+		for len(delta) > 0 {
+			c := delta[0]
+			delta = delta[1:]
+			n |= int(c&0x7f) << shift
+			shift += 7
+			if c&0x80 == 0 {
+				break
+			}
+		}
+		return n
+	}
+	if size() != len(base) {
+		return nil, fmt.Errorf("delta base size mismatch")
+	}
+	out := make([]byte, 0, size())
+
+	for len(delta) > 0 {
+		op := delta[0]
+		delta = delta[1:]
+		switch {
+		case op&0x80 != 0:
+			// copy, with the offset and size bytes present per bit
+			var offset, n int
+			for i := 0; i < 7; i++ {
+				if op&(1<<i) == 0 {
+					continue
+				}
+				if len(delta) == 0 {
+					return nil, fmt.Errorf("truncated delta")
+				}
+				if i < 4 {
+					offset |= int(delta[0]) << (8 * i)
+				} else {
+					n |= int(delta[0]) << (8 * (i - 4))
+				}
+				delta = delta[1:]
+			}
+			if n == 0 {
+				n = 0x10000
+			}
+			if offset+n > len(base) {
+				return nil, fmt.Errorf("delta copy out of range")
+			}
+			out = append(out, base[offset:offset+n]...)
+		case op != 0:
+			if int(op) > len(delta) {
+				return nil, fmt.Errorf("truncated delta")
+			}
+			out = append(out, delta[:op]...)
+			delta = delta[op:]
+		default:
+			return nil, fmt.Errorf("invalid delta instruction")
+		}
+	}
+	return out, nil
+}
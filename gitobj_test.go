@@ -0,0 +1,220 @@
+package godoc2md
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// runGit runs git in dir, isolated from the configuration of the user, and
+// returns its trimmed output.
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_CONFIG_GLOBAL="+os.DevNull,
+		"GIT_AUTHOR_NAME=Test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=Test", "GIT_COMMITTER_EMAIL=test@example.com",
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// newTestRepo creates a repository of three commits of a package whose files
+// change a little in each, so that they delta well once packed. The second
+// commit is tagged v1.0.0 with an annotated tag. Its objects are all loose.
+func newTestRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+
+	dir := t.TempDir()
+	runGit(t, dir, "init", "-q", "-b", "main")
+	if err := os.MkdirAll(path.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 3; i++ {
+		var src strings.Builder
+		fmt.Fprintf(&src, "// Package lib is at version %d.\npackage lib\n", i)
+		for n := 0; n < 100; n++ {
+			fmt.Fprintf(&src, "\n// F%d returns %d.\nfunc F%d() int { return %d }\n", n, n, n, n)
+		}
+		files := map[string]string{
+			"lib.go":     src.String(),
+			"sub/sub.go": fmt.Sprintf("// Package sub changed %d times.\npackage sub\n", i),
+			"README.md":  strings.Repeat("Read me.\n", 50*i),
+		}
+		for name, content := range files {
+			if err := os.WriteFile(path.Join(dir, name), []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		runGit(t, dir, "add", "-A")
+		runGit(t, dir, "commit", "-q", "-m", fmt.Sprintf("commit %d", i))
+		if i == 2 {
+			runGit(t, dir, "tag", "-a", "v1.0.0", "-m", "release")
+		}
+	}
+	return dir
+}
+
+// checkRevision checks that the commit and files of the revision rev of the
+// repository dir read by OpenRevision are those of git.
+func checkRevision(t *testing.T, dir, rev string) {
+	t.Helper()
+	r, err := OpenRevision(dir, rev)
+	if err != nil {
+		t.Fatalf("OpenRevision(%s): %v", rev, err)
+	}
+	if want := runGit(t, dir, "rev-parse", rev+"^{commit}"); r.Commit != want {
+		t.Errorf("%s: commit %s, want %s", rev, r.Commit, want)
+	}
+
+	for _, name := range strings.Split(runGit(t, dir, "ls-tree", "-r", "--name-only", rev), "\n") {
+		f, err := r.fs.Open("/" + name)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		got, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if want := runGit(t, dir, "show", rev+":"+name); strings.TrimSpace(string(got)) != want {
+			t.Errorf("%s:%s differs from git", rev, name)
+		}
+	}
+
+	list, err := r.fs.ReadDir("/")
+	if err != nil {
+		t.Fatalf("%s: ReadDir: %v", rev, err)
+	}
+	var names []string
+	for _, fi := range list {
+		names = append(names, fi.Name())
+	}
+	sort.Strings(names)
+	if got, want := strings.Join(names, "\n"), runGit(t, dir, "ls-tree", "--name-only", rev); got != want {
+		t.Errorf("%s: ReadDir lists %q, want %q", rev, got, want)
+	}
+}
+
+// packEntryTypes counts the types of the entries of the packs of r.
+func packEntryTypes(t *testing.T, r *gitRepo) map[byte]int {
+	t.Helper()
+	types := map[byte]int{}
+	for _, p := range r.packs {
+		data, err := os.ReadFile(p.filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, offset := range p.offsets {
+			types[(data[offset]>>4)&7]++
+		}
+	}
+	return types
+}
+
+func TestRevisionLooseObjects(t *testing.T) {
+	dir := newTestRepo(t)
+	for _, rev := range []string{"HEAD", "main", "v1.0.0", runGit(t, dir, "rev-parse", "HEAD~2")} {
+		checkRevision(t, dir, rev)
+	}
+}
+
+func TestRevisionPackedObjects(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		useOffset string
+		delta     byte
+	}{
+		{"ofs-delta", "true", gitOfsDelta},
+		{"ref-delta", "false", gitRefDelta},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := newTestRepo(t)
+			runGit(t, dir, "-c", "repack.useDeltaBaseOffset="+tt.useOffset, "repack", "-a", "-d", "-f", "-q")
+			runGit(t, dir, "prune-packed")
+			runGit(t, dir, "pack-refs", "--all")
+
+			r, err := openGitRepo(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if types := packEntryTypes(t, r); types[tt.delta] == 0 {
+				t.Fatalf("no entry of type %d in the pack: %v", tt.delta, types)
+			}
+
+			for _, rev := range []string{"HEAD", "main", "v1.0.0", runGit(t, dir, "rev-parse", "--short", "HEAD~2")} {
+				checkRevision(t, dir, rev)
+			}
+		})
+	}
+}
+
+func TestResolveRevision(t *testing.T) {
+	dir := newTestRepo(t)
+	r, err := openGitRepo(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tag := runGit(t, dir, "rev-parse", "v1.0.0")
+	commit := runGit(t, dir, "rev-parse", "v1.0.0^{commit}")
+	if tag == commit {
+		t.Fatalf("v1.0.0 is not an annotated tag")
+	}
+	for _, rev := range []string{"v1.0.0", "refs/tags/v1.0.0", tag, tag[:7], commit[:10]} {
+		h, err := r.resolve(rev)
+		if err != nil {
+			t.Errorf("resolve(%s): %v", rev, err)
+			continue
+		}
+		if h.String() != commit {
+			t.Errorf("resolve(%s) = %s, want %s", rev, h, commit)
+		}
+	}
+
+	tree := runGit(t, dir, "rev-parse", "HEAD^{tree}")
+	for _, rev := range []string{"v9.9.9", "abc", tree} {
+		if h, err := r.resolve(rev); err == nil {
+			t.Errorf("resolve(%s) = %s, want an error", rev, h)
+		}
+	}
+}
+
+func TestApplyGitDelta(t *testing.T) {
+	base := []byte("hello, world")
+	// sizes 12 and 12, copy 7 bytes from 0, then insert "there"
+	delta := []byte{12, 12, 0x90, 7, 5, 't', 'h', 'e', 'r', 'e'}
+	got, err := applyGitDelta(base, delta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "hello, there" {
+		t.Errorf("applyGitDelta = %q, want %q", got, "hello, there")
+	}
+
+	for _, delta := range [][]byte{
+		{11, 5, 5, 'h'},
+		{12, 5, 0x91, 10, 7},
+		{12, 5, 0},
+	} {
+		if got, err := applyGitDelta(base, delta); err == nil {
+			t.Errorf("applyGitDelta(%v) = %q, want an error", delta, got)
+		}
+	}
+}
@@ -2,7 +2,10 @@ module github.com/chriswgerber/godoc2md
 
 go 1.20
 
-require golang.org/x/tools v0.0.0-20181011021141-0e57ebad1d6b
+require (
+	github.com/yuin/goldmark v1.7.8
+	golang.org/x/tools v0.0.0-20181011021141-0e57ebad1d6b
+)
 
 require (
 	github.com/codegangsta/inject v0.0.0-20150114235600-33e0aa1cb7c0 // indirect
@@ -0,0 +1,206 @@
+package godoc2md
+
+import (
+	"fmt"
+	"go/doc"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// helperFuncs returns the general purpose helpers available to templates, in
+// addition to the godoc2md specific funcs of Methods. Helpers taking the
+// value to operate on as their last argument can be used in pipelines, as in
+// `{{.Doc | synopsis | indent 2}}`.
+func (t TemplateUtils) helperFuncs() map[string]interface{} {
+	return map[string]interface{}{
+		"synopsis": t.Synopsis,
+		"upper":    strings.ToUpper,
+		"lower":    strings.ToLower,
+		"title":    t.Title,
+		"camel":    t.Camel,
+		"snake":    t.Snake,
+		"join":     t.Join,
+		"default":  t.Default,
+		"indent":   t.Indent,
+		"replace":  t.Replace,
+		"contains": t.Contains,
+		"list":     t.List,
+		"dict":     t.Dict,
+		"env":      os.Getenv,
+		"relpath":  t.RelPath,
+		"anchor":   t.Anchor,
+	}
+}
+
+// Synopsis returns the first sentence of text, as `go doc` shows it in
+// package listings.
+func (t TemplateUtils) Synopsis(text string) string {
+	return doc.Synopsis(text)
+}
+
+// Title upper cases the first letter of every word of text.
+func (t TemplateUtils) Title(text string) string {
+	runes := []rune(text)
+	for i, r := range runes {
+		if i == 0 || unicode.IsSpace(runes[i-1]) || runes[i-1] == '-' || runes[i-1] == '_' {
+			runes[i] = unicode.ToUpper(r)
+		}
+	}
+	return string(runes)
+}
+
+// Camel converts text to camel case, e.g. "ReadAll" and "read all" both
+// become "readAll".
+func (t TemplateUtils) Camel(text string) string {
+	words := splitWords(text)
+	for i, word := range words {
+		word = strings.ToLower(word)
+		if i > 0 {
+			word = t.Title(word)
+		}
+		words[i] = word
+	}
+	return strings.Join(words, "")
+}
+
+// Snake converts text to snake case, e.g. "ReadAll" and "read all" both
+// become "read_all".
+func (t TemplateUtils) Snake(text string) string {
+	words := splitWords(text)
+	for i, word := range words {
+		words[i] = strings.ToLower(word)
+	}
+	return strings.Join(words, "_")
+}
+
+// splitWords splits text into words at whitespace, punctuation and the
+// lower to upper case transitions of Go identifiers. Runs of upper case
+// letters, such as "HTTP" in "HTTPServer", are kept together.
+func splitWords(text string) []string {
+	var (
+		words []string
+		word  []rune
+	)
+	runes := []rune(text)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if len(word) > 0 {
+				words = append(words, string(word))
+				word = word[:0]
+			}
+			continue
+		}
+		if unicode.IsUpper(r) && len(word) > 0 {
+			prev := word[len(word)-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if !unicode.IsUpper(prev) || nextLower {
+				words = append(words, string(word))
+				word = word[:0]
+			}
+		}
+		word = append(word, r)
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+	return words
+}
+
+// Join joins the elements of the slice or array items, formatted with
+// fmt.Sprint, with sep.
+func (t TemplateUtils) Join(sep string, items interface{}) (string, error) {
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return "", fmt.Errorf("join: can't join %T", items)
+	}
+
+	parts := make([]string, v.Len())
+	for i := range parts {
+		parts[i] = fmt.Sprint(v.Index(i).Interface())
+	}
+	return strings.Join(parts, sep), nil
+}
+
+// Default returns value, or def if value is empty: false, 0, a nil pointer
+// or interface, or an empty string, slice or map.
+func (t TemplateUtils) Default(def, value interface{}) interface{} {
+	if truth, ok := template.IsTrue(value); !ok || !truth {
+		return def
+	}
+	return value
+}
+
+// Indent indents every non-empty line of text by n spaces.
+func (t TemplateUtils) Indent(n int, text string) string {
+	prefix := strings.Repeat(" ", n)
+	lines := strings.SplitAfter(text, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+// Replace replaces all occurrences of old in text with new.
+func (t TemplateUtils) Replace(old, new, text string) string {
+	return strings.Replace(text, old, new, -1)
+}
+
+// Contains reports whether substr is within text.
+func (t TemplateUtils) Contains(substr, text string) bool {
+	return strings.Contains(text, substr)
+}
+
+// List returns its arguments as a slice. It's named list, as slice is the
+// text/template builtin slicing an existing slice.
+func (t TemplateUtils) List(items ...interface{}) []interface{} {
+	return items
+}
+
+// Dict returns a map of its arguments, which must be alternating string keys
+// and values, e.g. `dict "Name" .Name "Level" 2`. It's useful to pass more
+// than one value to a template block.
+func (t TemplateUtils) Dict(pairs ...interface{}) (map[string]interface{}, error) {
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("dict: odd number of arguments")
+	}
+
+	dict := make(map[string]interface{}, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict: key %v is %T, not string", pairs[i], pairs[i])
+		}
+		dict[key] = pairs[i+1]
+	}
+	return dict, nil
+}
+
+// RelPath returns the relative link from the page being rendered to name, a
+// path relative to the output directory. It lets pages link to shared files,
+// such as `{{relpath "CHANGELOG.md"}}`, wherever they are written, including
+// the pages of the split layout.
+func (t TemplateUtils) RelPath(name string) string {
+	rel, err := filepath.Rel(
+		filepath.FromSlash(path.Clean("/"+path.Dir(t.pagePath))),
+		filepath.FromSlash(path.Clean("/"+name)),
+	)
+	if err != nil {
+		return name
+	}
+
+	return filepath.ToSlash(rel)
+}
+
+// Anchor returns the id the configured forge generates for a Markdown heading
+// with the text heading, so that templates can link to headings they don't
+// mark up with an explicit anchor.
+func (t TemplateUtils) Anchor(heading string) string {
+	return t.forge.Slug(heading)
+}
@@ -0,0 +1,149 @@
+package godoc2md
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCaseConversions(t *testing.T) {
+	var u TemplateUtils
+	for _, tt := range []struct {
+		in, title, camel, snake string
+	}{
+		{"ReadAll", "ReadAll", "readAll", "read_all"},
+		{"read all", "Read All", "readAll", "read_all"},
+		{"HTTPServer", "HTTPServer", "httpServer", "http_server"},
+		{"parseURL", "ParseURL", "parseUrl", "parse_url"},
+		{"kebab-case_name", "Kebab-Case_Name", "kebabCaseName", "kebab_case_name"},
+		{"", "", "", ""},
+	} {
+		if got := u.Title(tt.in); got != tt.title {
+			t.Errorf("Title(%q) = %q, want %q", tt.in, got, tt.title)
+		}
+		if got := u.Camel(tt.in); got != tt.camel {
+			t.Errorf("Camel(%q) = %q, want %q", tt.in, got, tt.camel)
+		}
+		if got := u.Snake(tt.in); got != tt.snake {
+			t.Errorf("Snake(%q) = %q, want %q", tt.in, got, tt.snake)
+		}
+	}
+}
+
+func TestSplitWords(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want []string
+	}{
+		{"ReadAll", []string{"Read", "All"}},
+		{"HTTPServer", []string{"HTTP", "Server"}},
+		{"ServeHTTP", []string{"Serve", "HTTP"}},
+		{"utf8Valid", []string{"utf8", "Valid"}},
+		{"  read, all.  ", []string{"read", "all"}},
+		{"über_Größe", []string{"über", "Größe"}},
+		{"", nil},
+	} {
+		if got := splitWords(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitWords(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestJoin(t *testing.T) {
+	var u TemplateUtils
+	for _, tt := range []struct {
+		items interface{}
+		want  string
+	}{
+		{[]string{"a", "b"}, "a, b"},
+		{[]int{1, 2, 3}, "1, 2, 3"},
+		{[2]bool{true, false}, "true, false"},
+		{[]string{}, ""},
+	} {
+		got, err := u.Join(", ", tt.items)
+		if err != nil || got != tt.want {
+			t.Errorf("Join(%v) = %q, %v, want %q", tt.items, got, err, tt.want)
+		}
+	}
+
+	for _, items := range []interface{}{"ab", 1, nil, map[string]int{"a": 1}} {
+		if got, err := u.Join(", ", items); err == nil {
+			t.Errorf("Join(%v) = %q, want an error", items, got)
+		}
+	}
+}
+
+func TestDefault(t *testing.T) {
+	var u TemplateUtils
+	var nilPtr *int
+	for _, value := range []interface{}{nil, "", 0, false, nilPtr, []string{}, map[string]int{}} {
+		if got := u.Default("def", value); got != "def" {
+			t.Errorf("Default(%#v) = %v, want the default", value, got)
+		}
+	}
+	for _, value := range []interface{}{"x", 1, true, []string{"a"}} {
+		if got := u.Default("def", value); !reflect.DeepEqual(got, value) {
+			t.Errorf("Default(%#v) = %v, want the value", value, got)
+		}
+	}
+}
+
+func TestIndent(t *testing.T) {
+	var u TemplateUtils
+	for _, tt := range []struct {
+		n        int
+		in, want string
+	}{
+		{2, "a\nb", "  a\n  b"},
+		{2, "a\n\n b\n", "  a\n\n   b\n"},
+		{4, "  \n", "  \n"},
+		{0, "a\nb", "a\nb"},
+		{3, "", ""},
+	} {
+		if got := u.Indent(tt.n, tt.in); got != tt.want {
+			t.Errorf("Indent(%d, %q) = %q, want %q", tt.n, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDict(t *testing.T) {
+	var u TemplateUtils
+	got, err := u.Dict("Name", "x", "Level", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]interface{}{"Name": "x", "Level": 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Dict = %v, want %v", got, want)
+	}
+	if got, err := u.Dict(); err != nil || len(got) != 0 {
+		t.Errorf("Dict() = %v, %v, want an empty dict", got, err)
+	}
+
+	for _, pairs := range [][]interface{}{
+		{"Name"},
+		{"Name", "x", "Level"},
+		{1, "x"},
+		{nil, "x"},
+	} {
+		if got, err := u.Dict(pairs...); err == nil {
+			t.Errorf("Dict(%v) = %v, want an error", pairs, got)
+		}
+	}
+}
+
+func TestRelPath(t *testing.T) {
+	for _, tt := range []struct {
+		page, name, want string
+	}{
+		{"README.md", "CHANGELOG.md", "CHANGELOG.md"},
+		{"pkg/a/README.md", "CHANGELOG.md", "../../CHANGELOG.md"},
+		{"pkg/a/type-T.md", "/CHANGELOG.md", "../../CHANGELOG.md"},
+		{"pkg/a/funcs.md", "pkg/b/README.md", "../b/README.md"},
+		{"pkg/a/README.md", "pkg/a/funcs.md", "funcs.md"},
+		{"pkg/a/README.md", "pkg/a/sub/../img.png", "img.png"},
+	} {
+		u := TemplateUtils{pagePath: tt.page}
+		if got := u.RelPath(tt.name); got != tt.want {
+			t.Errorf("RelPath(%q) from %s = %q, want %q", tt.name, tt.page, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package godoc2md
+
+import (
+	"bytes"
+	"go/scanner"
+	"go/token"
+	"text/template" // for HTMLEscape
+)
+
+// HighlightGo escapes Go source code for HTML and wraps keywords, literals and
+// comments in `<span>` elements with the classes `kw`, `str`, `num` and `com`,
+// so that they can be styled by the page.
+func (t TemplateUtils) HighlightGo(src string) string {
+	var (
+		buf  bytes.Buffer
+		s    scanner.Scanner
+		last int
+	)
+
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+	s.Init(file, []byte(src), nil, scanner.ScanComments)
+
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+
+		class := ""
+		switch {
+		case tok == token.COMMENT:
+			class = "com"
+		case tok.IsKeyword():
+			class = "kw"
+		case tok == token.STRING || tok == token.CHAR:
+			class = "str"
+		case tok == token.INT || tok == token.FLOAT || tok == token.IMAG:
+			class = "num"
+		}
+		if class == "" {
+			continue
+		}
+
+		offset := file.Offset(pos)
+		template.HTMLEscape(&buf, []byte(src[last:offset]))
+		buf.WriteString(`<span class="` + class + `">`)
+		template.HTMLEscape(&buf, []byte(lit))
+		buf.WriteString(`</span>`)
+		last = offset + len(lit)
+	}
+	template.HTMLEscape(&buf, []byte(src[last:]))
+
+	return buf.String()
+}
@@ -0,0 +1,144 @@
+package godoc2md
+
+// htmlTemplate is the built-in template of the `html` format. It renders a
+// standalone page, with a navigation sidebar listing every package of a
+// multi-package run.
+var htmlTemplate = `{{with .PDoc -}}
+<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{if $.IsMain}}{{base .ImportPath | html}}{{else}}{{html .Name}}{{end}} - {{html .ImportPath}}</title>
+<style>
+body { margin: 0; font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; line-height: 1.5; color: #24292f; }
+a { color: #0969da; text-decoration: none; }
+a:hover { text-decoration: underline; }
+nav { position: fixed; top: 0; bottom: 0; left: 0; width: 16rem; overflow-y: auto; padding: 1rem; background: #f6f8fa; border-right: 1px solid #d0d7de; box-sizing: border-box; }
+nav ul { list-style: none; padding: 0; margin: 0; }
+nav li { margin: .25rem 0; word-break: break-all; }
+nav li.current { font-weight: 600; }
+main { padding: 1rem 2rem; max-width: 60rem; }
+nav + main { margin-left: 16rem; }
+pre { padding: 1rem; overflow-x: auto; background: #f6f8fa; border-radius: 6px; }
+code { font-family: ui-monospace, SFMono-Regular, Menlo, Consolas, monospace; font-size: 85%; }
+.kw { color: #cf222e; }
+.str { color: #0a3069; }
+.num { color: #0550ae; }
+.com { color: #6e7781; font-style: italic; }
+.deprecated { text-decoration: line-through; }
+.deprecated-notice { margin: 1rem 0; padding: 0 1rem; border-left: 4px solid #bf8700; background: #fff8c5; }
+footer { margin-top: 2rem; font-size: 85%; color: #6e7781; }
+</style>
+</head>
+<body>
+{{- $current := .ImportPath}}{{with site_pages}}
+<nav>
+<h2>Packages</h2>
+<ul>
+{{- range .}}
+<li{{if eq .ImportPath $current}} class="current"{{end}}><a href="{{page_link $current .ImportPath | html}}">{{html .ImportPath}}</a></li>
+{{- end}}
+</ul>
+</nav>
+{{- end}}
+<main>
+{{- if $.IsMain}}
+<h1>{{base .ImportPath | html}}</h1>
+{{comment .Doc}}
+{{- else}}
+<h1 id="pkg-top">package {{html .Name}}</h1>
+<p><code>import "{{html .ImportPath}}"</code></p>
+
+<h2 id="pkg-overview">Overview</h2>
+{{doc .Doc}}
+
+<h2 id="pkg-index">Index</h2>
+<ul>
+{{- if .Consts}}
+<li><a href="#pkg-constants">Constants</a></li>
+{{- end}}{{if .Vars}}
+<li><a href="#pkg-variables">Variables</a></li>
+{{- end}}{{range .Funcs}}
+<li><a href="#{{symbol_anchor "" .Name}}"{{if deprecated .Doc}} class="deprecated"{{end}}>{{node_html $ .Decl false | sanitize}}</a></li>
+{{- end}}{{range .Types}}{{$tname := .Name}}{{$tname_html := html .Name}}
+<li><a href="#{{symbol_anchor "" $tname}}"{{if deprecated .Doc}} class="deprecated"{{end}}>type {{$tname_html}}</a>
+<ul>
+{{- if is_enum .}}
+<li><a href="#{{symbol_anchor $tname "values"}}">Values</a></li>
+{{- end}}{{range .Funcs}}
+<li><a href="#{{symbol_anchor "" .Name}}"{{if deprecated .Doc}} class="deprecated"{{end}}>{{node_html $ .Decl false | sanitize}}</a></li>
+{{- end}}{{range .Methods}}
+<li><a href="#{{symbol_anchor $tname .Name}}"{{if deprecated .Doc}} class="deprecated"{{end}}>{{node_html $ .Decl false | sanitize}}</a></li>
+{{- end}}
+</ul>
+</li>
+{{- end}}{{range $marker, $item := $.Notes}}
+<li><a href="#pkg-note-{{$marker}}">{{noteTitle $marker | html}}s</a></li>
+{{- end}}
+</ul>
+{{with .Filenames}}
+<h3 id="pkg-files">Package files</h3>
+<p>{{range .}}<a href="{{.|srcfile_url|html}}">{{.|filename|html}}</a> {{end}}</p>
+{{end}}
+{{- with .Consts}}
+<h2 id="pkg-constants">Constants</h2>
+{{range .}}<pre><code class="language-go">{{node $ .Decl | highlight_go}}</code></pre>
+{{platforms $ .}}{{doc .Doc}}
+{{end}}{{end}}
+{{- with .Vars}}
+<h2 id="pkg-variables">Variables</h2>
+{{range .}}<pre><code class="language-go">{{node $ .Decl | highlight_go}}</code></pre>
+{{platforms $ .}}{{doc .Doc}}
+{{end}}{{end}}
+{{- range .Funcs}}{{$name_html := html .Name}}
+<h2 id="{{symbol_anchor "" .Name}}">func <a href="{{get_full_url $ .Decl | html}}">{{$name_html}}</a></h2>
+<pre><code class="language-go">{{node $ .Decl | highlight_go}}</code></pre>
+{{platforms $ .}}{{doc .Doc}}
+{{- end}}
+{{- range .Types}}{{$tname := .Name}}{{$tname_html := html .Name}}
+<h2 id="{{symbol_anchor "" $tname}}">type <a href="{{get_full_url $ .Decl | html}}">{{$tname_html}}</a></h2>
+<pre><code class="language-go">{{node $ .Decl | highlight_go}}</code></pre>
+{{platforms $ .}}{{doc .Doc}}
+{{- if is_enum .}}
+<h3 id="{{symbol_anchor $tname "values"}}">Values</h3>
+{{range .Consts}}<pre><code class="language-go">{{node $ .Decl | highlight_go}}</code></pre>
+{{platforms $ .}}{{doc .Doc}}
+{{end}}{{if has_stringer .}}<p><code>{{$tname_html}}</code> values print by name through <a href="#{{symbol_anchor $tname "String"}}">String</a>.</p>
+{{end}}
+{{- else}}{{range .Consts}}
+<pre><code class="language-go">{{node $ .Decl | highlight_go}}</code></pre>
+{{platforms $ .}}{{doc .Doc}}
+{{- end}}{{end}}{{range .Vars}}
+<pre><code class="language-go">{{node $ .Decl | highlight_go}}</code></pre>
+{{platforms $ .}}{{doc .Doc}}
+{{- end}}
+{{- range .Funcs}}{{$name_html := html .Name}}
+<h3 id="{{symbol_anchor "" .Name}}">func <a href="{{get_full_url $ .Decl | html}}">{{$name_html}}</a></h3>
+<pre><code class="language-go">{{node $ .Decl | highlight_go}}</code></pre>
+{{platforms $ .}}{{doc .Doc}}
+{{- end}}
+{{- range .Methods}}{{$name_html := html .Name}}
+<h3 id="{{symbol_anchor $tname .Name}}">func ({{html .Recv}}) <a href="{{get_full_url $ .Decl | html}}">{{$name_html}}</a></h3>
+<pre><code class="language-go">{{node $ .Decl | highlight_go}}</code></pre>
+{{platforms $ .}}{{doc .Doc}}
+{{- end}}
+{{- end}}
+{{- range $marker, $content := $.Notes}}
+<h2 id="pkg-note-{{$marker}}">{{noteTitle $marker | html}}s</h2>
+<ul>
+{{- range .}}
+<li>{{with .UID}}<strong>{{html .}}</strong> {{end}}(<a href="{{note_url $ . | html}}">source</a>): {{html .Body}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- end}}
+<footer>
+Created: {{current_time | html}}<br>
+Generated by <a href="http://github.com/chriswgerber/godoc2md">godoc2md</a>
+</footer>
+</main>
+</body>
+</html>
+{{end -}}
+`
@@ -0,0 +1,269 @@
+package godoc2md
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"html"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// The values of the `-checkLinks` flag.
+const (
+	checkLinksWarn  = "warn"
+	checkLinksError = "error"
+)
+
+// linkSyntax describes how the anchors and internal links of an output format
+// are written.
+type linkSyntax struct {
+	// anchors match the explicit anchors of a page, in their first group.
+	anchors []*regexp.Regexp
+	// links match the links of a page, in their first group.
+	links []*regexp.Regexp
+	// refs match the cross references of a page to its own anchor ids, in
+	// their first group.
+	refs []*regexp.Regexp
+	// headings matches Markdown headings, which the forge gives an id of
+	// its own. It is nil for formats without implicit heading ids.
+	headings *regexp.Regexp
+	// fence matches the lines opening and closing code blocks, whose
+	// content is not checked.
+	fence *regexp.Regexp
+}
+
+var (
+	htmlAnchorRxs = []*regexp.Regexp{
+		regexp.MustCompile(`<a name="([^"]+)"`),
+		regexp.MustCompile(`\sid="([^"]+)"`),
+	}
+	htmlLinkRx   = regexp.MustCompile(`<a href="([^"]+)"`)
+	htmlTagRx    = regexp.MustCompile(`<[^>]*>`)
+	mdLinkTextRx = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+)
+
+var linkSyntaxes = map[string]linkSyntax{
+	"md": {
+		anchors: htmlAnchorRxs,
+		links: []*regexp.Regexp{
+			regexp.MustCompile(`\]\(([^)\s]+)(?:\s+"[^"]*")?\)`),
+			htmlLinkRx,
+		},
+		headings: regexp.MustCompile(`^#{1,6}\s+(.+?)\s*#*$`),
+		fence:    regexp.MustCompile("^```"),
+	},
+	"html": {
+		anchors: htmlAnchorRxs,
+		links:   []*regexp.Regexp{htmlLinkRx},
+	},
+	"adoc": {
+		anchors: []*regexp.Regexp{
+			regexp.MustCompile(`\[\[([^\],]+)\]\]`),
+			regexp.MustCompile(`^\[[a-z]*#([^\],]+)\]`),
+		},
+		links: []*regexp.Regexp{
+			regexp.MustCompile(`<<([^,>#]*#[^,>]*)[,>]`),
+			regexp.MustCompile(`(?:xref|link):([^\[\s]+)\[`),
+		},
+		refs:  []*regexp.Regexp{regexp.MustCompile(`<<([^,>#]+)[,>]`)},
+		fence: regexp.MustCompile(`^----$`),
+	},
+	"rst": {
+		anchors: []*regexp.Regexp{regexp.MustCompile(`^\.\. _([^:]+):$`)},
+		links:   []*regexp.Regexp{regexp.MustCompile("`[^`]*<([^>]*[^>_])>`__?")},
+		refs:    []*regexp.Regexp{regexp.MustCompile("`[^`]*<([^>]+)_>`_")},
+	},
+}
+
+// DanglingLink is an internal link of the output whose target was not
+// generated.
+type DanglingLink struct {
+	// Page is the file the link is found in, relative to the output
+	// directory, or empty for output written to stdout.
+	Page string
+	// Line is the line number of the link.
+	Line int
+	// Target is the link destination, as written.
+	Target string
+}
+
+func (l DanglingLink) String() string {
+	page := l.Page
+	if page == "" {
+		page = "<stdout>"
+	}
+	return fmt.Sprintf("%s:%d: dangling link to %s", page, l.Line, l.Target)
+}
+
+type pageLink struct {
+	line   int
+	target string
+}
+
+type checkedPage struct {
+	anchors map[string]bool
+	links   []pageLink
+}
+
+// LinkChecker checks the internal links of generated pages: links to anchors
+// of the same page, and relative links to other pages, or files, and their
+// anchors.
+type LinkChecker struct {
+	root   string
+	syntax linkSyntax
+	forge  Forge
+	pages  map[string]*checkedPage
+}
+
+// NewLinkChecker returns a link checker for pages in the output format named
+// format, written below the directory root. Relative links that don't point
+// to a checked page are looked up on disk below root.
+func NewLinkChecker(root, format string, forge Forge) *LinkChecker {
+	return &LinkChecker{
+		root:   root,
+		syntax: linkSyntaxes[format],
+		forge:  forge,
+		pages:  map[string]*checkedPage{},
+	}
+}
+
+// AddPage collects the anchors and links of the page called name, a slash
+// separated path relative to the output directory. Output written to stdout
+// is added with an empty name, and only its links to anchors are checked.
+func (c *LinkChecker) AddPage(name string, content []byte) {
+	page := &checkedPage{anchors: map[string]bool{}}
+	headings := NewAnchors(c.forge)
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(nil, len(content)+1)
+	inFence := false
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if c.syntax.fence != nil && c.syntax.fence.MatchString(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		for _, rx := range c.syntax.anchors {
+			for _, m := range rx.FindAllStringSubmatch(line, -1) {
+				page.anchors[m[1]] = true
+			}
+		}
+		if c.syntax.headings != nil {
+			if m := c.syntax.headings.FindStringSubmatch(line); m != nil {
+				page.anchors[headings.Heading(headingText(m[1]))] = true
+			}
+		}
+		for _, rx := range c.syntax.links {
+			for _, m := range rx.FindAllStringSubmatch(line, -1) {
+				page.links = append(page.links, pageLink{line: n, target: html.UnescapeString(m[1])})
+			}
+		}
+		for _, rx := range c.syntax.refs {
+			for _, m := range rx.FindAllStringSubmatch(line, -1) {
+				page.links = append(page.links, pageLink{line: n, target: "#" + m[1]})
+			}
+		}
+	}
+
+	c.pages[name] = page
+}
+
+// Check returns the links of all added pages whose target is missing, ordered
+// by page and line.
+func (c *LinkChecker) Check() []DanglingLink {
+	names := make([]string, 0, len(c.pages))
+	for name := range c.pages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var dangling []DanglingLink
+	for _, name := range names {
+		for _, link := range c.pages[name].links {
+			if !c.resolves(name, link.target) {
+				dangling = append(dangling, DanglingLink{Page: name, Line: link.line, Target: link.target})
+			}
+		}
+	}
+	return dangling
+}
+
+// resolves reports whether the link target, found on the page called name,
+// points to a generated anchor or file. External links are not checked.
+func (c *LinkChecker) resolves(name, target string) bool {
+	u, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+	if u.Scheme != "" || u.Host != "" || strings.HasPrefix(target, "//") {
+		return true
+	}
+
+	if u.Path == "" {
+		return u.Fragment == "" || c.pages[name].anchors[u.Fragment]
+	}
+	if name == "" {
+		// the location of output written to stdout is unknown
+		return true
+	}
+
+	dest := path.Join(path.Dir(name), u.Path)
+	if page, ok := c.pages[dest]; ok {
+		return u.Fragment == "" || page.anchors[u.Fragment]
+	}
+
+	fi, err := os.Stat(filepath.Join(c.root, filepath.FromSlash(dest)))
+	if err != nil {
+		return false
+	}
+	if fi.IsDir() && u.Fragment != "" {
+		return false
+	}
+	return true
+}
+
+// newLinkChecker returns a link checker for the output configured by cfg.
+func newLinkChecker(cfg *Cli) (*LinkChecker, error) {
+	switch *cfg.CheckLinks {
+	case checkLinksWarn, checkLinksError:
+	default:
+		return nil, fmt.Errorf("unknown checkLinks mode %q, must be one of: %s, %s", *cfg.CheckLinks, checkLinksWarn, checkLinksError)
+	}
+
+	forge, err := GetForge(*cfg.Forge)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewLinkChecker(*cfg.OutDir, *cfg.Format, forge), nil
+}
+
+// reportLinks logs the dangling links found in the output and, if the
+// `-checkLinks` flag asks for it, fails.
+func reportLinks(cfg *Cli, dangling []DanglingLink) error {
+	for _, link := range dangling {
+		log.Print(link)
+	}
+	if *cfg.CheckLinks == checkLinksError && len(dangling) > 0 {
+		return fmt.Errorf("found %d dangling links", len(dangling))
+	}
+	return nil
+}
+
+// headingText returns the text of a Markdown heading as forges slug it,
+// without its HTML tags and link destinations.
+func headingText(heading string) string {
+	text := htmlTagRx.ReplaceAllString(heading, "")
+	return mdLinkTextRx.ReplaceAllString(text, "$1")
+}
@@ -0,0 +1,154 @@
+package godoc2md
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLinkChecker(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "LICENSE"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		format string
+		pages  map[string]string
+		want   []DanglingLink
+	}{
+		{
+			format: "md",
+			pages: map[string]string{
+				"README.md": `# Example
+
+[Usage](#usage), <a href="#pkg-index">Index</a>
+[Broken](#missing)
+[Sub](sub/README.md#sub-anchor), [Details](sub/README.md#details "Details")
+[Bad sub](sub/README.md#nope)
+[Missing](other/README.md)
+[License](LICENSE)
+[External](https://example.com/#missing)
+` + "```go" + `
+fmt.Println("[Fenced](#fenced)")
+` + "```" + `
+
+## <a name="pkg-index">Index</a>
+## Usage
+`,
+				"sub/README.md": `<a name="sub-anchor"></a>
+## Details
+
+[Back](../README.md#usage)
+[Back broken](../README.md#gone)
+`,
+			},
+			want: []DanglingLink{
+				{Page: "README.md", Line: 4, Target: "#missing"},
+				{Page: "README.md", Line: 6, Target: "sub/README.md#nope"},
+				{Page: "README.md", Line: 7, Target: "other/README.md"},
+				{Page: "sub/README.md", Line: 5, Target: "../README.md#gone"},
+			},
+		},
+		{
+			format: "html",
+			pages: map[string]string{
+				"index.html": `<h1 id="top">Example</h1>
+<p><a href="#top">Top</a> <a href="#missing">Broken</a></p>
+<p><a href="sub/index.html#sub-anchor">Sub</a></p>
+<p><a href="sub/index.html#nope">Bad sub</a></p>
+<p><a href="other/index.html">Missing</a></p>
+<p><a href="https://example.com/">External</a> <a href="LICENSE">License</a></p>
+`,
+				"sub/index.html": `<a name="sub-anchor"></a>
+<a href="../index.html#top">Back</a>
+<a href="../index.html#gone">Back broken</a>
+`,
+			},
+			want: []DanglingLink{
+				{Page: "index.html", Line: 2, Target: "#missing"},
+				{Page: "index.html", Line: 4, Target: "sub/index.html#nope"},
+				{Page: "index.html", Line: 5, Target: "other/index.html"},
+				{Page: "sub/index.html", Line: 3, Target: "../index.html#gone"},
+			},
+		},
+		{
+			format: "adoc",
+			pages: map[string]string{
+				"index.adoc": `[[top]]
+== Example
+
+<<top,Top>> and <<missing,Broken>>
+<<sub/index.adoc#sub-anchor,Sub>>
+<<sub/index.adoc#nope,Bad sub>>
+xref:other/index.adoc[Missing]
+link:https://example.com/[External] link:LICENSE[License]
+----
+<<fenced>>
+----
+`,
+				"sub/index.adoc": `[#sub-anchor]
+== Sub
+
+<<../index.adoc#top,Back>>
+<<../index.adoc#gone,Back broken>>
+`,
+			},
+			want: []DanglingLink{
+				{Page: "index.adoc", Line: 4, Target: "#missing"},
+				{Page: "index.adoc", Line: 6, Target: "sub/index.adoc#nope"},
+				{Page: "index.adoc", Line: 7, Target: "other/index.adoc"},
+				{Page: "sub/index.adoc", Line: 5, Target: "../index.adoc#gone"},
+			},
+		},
+		{
+			format: "rst",
+			pages: map[string]string{
+				"index.rst": ".. _top:\n" + `
+Example
+=======
+
+` + "`Top <top_>`_ and `Broken <missing_>`_" + `
+` + "`Sub <sub/index.rst#sub-anchor>`__" + `
+` + "`Bad sub <sub/index.rst#nope>`__" + `
+` + "`Missing <other/index.rst>`__" + `
+` + "`External <https://example.com/>`__ `License <LICENSE>`__" + `
+`,
+				"sub/index.rst": ".. _sub-anchor:\n" + `
+` + "`Back <../index.rst#top>`__" + `
+` + "`Back broken <../index.rst#gone>`__" + `
+`,
+			},
+			want: []DanglingLink{
+				{Page: "index.rst", Line: 6, Target: "#missing"},
+				{Page: "index.rst", Line: 8, Target: "sub/index.rst#nope"},
+				{Page: "index.rst", Line: 9, Target: "other/index.rst"},
+				{Page: "sub/index.rst", Line: 4, Target: "../index.rst#gone"},
+			},
+		},
+	} {
+		checker := NewLinkChecker(root, tt.format, ForgeGitHub)
+		for name, content := range tt.pages {
+			checker.AddPage(name, []byte(content))
+		}
+		if got := checker.Check(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Check() =\n%v\nwant\n%v", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestLinkCheckerStdout(t *testing.T) {
+	checker := NewLinkChecker(t.TempDir(), "md", ForgeGitLab)
+	checker.AddPage("", []byte(`## Getting  Started
+
+[Start](#getting-started)
+[Broken](#getting--started)
+[Other page](other/README.md#anything)
+`))
+
+	want := []DanglingLink{{Line: 4, Target: "#getting--started"}}
+	if got := checker.Check(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Check() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,450 @@
+package godoc2md
+
+import (
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/printer"
+	"go/token"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/tools/godoc"
+)
+
+// ModelVersion is the version of the package documentation model. It is bumped
+// whenever a field is removed or changes meaning; fields may be added without
+// changing the version.
+const ModelVersion = 1
+
+// Package is godoc2md's model of the documentation of a single package. It is
+// the schema of the `json` output format, and the data custom templates are
+// executed with when `-model` is set.
+//
+// Unlike godoc.PageInfo, the model only changes in the ways allowed by
+// ModelVersion, so templates written against it keep working across godoc2md
+// releases. Declarations are pre-rendered as Go source (Decl), doc comments as
+// Markdown (DocMD), and every symbol carries its anchor and source URL.
+type Package struct {
+	Version    int    `json:"version"`
+	Name       string `json:"name"`
+	ImportPath string `json:"importPath"`
+	IsCommand  bool   `json:"isCommand"`
+	Synopsis   string `json:"synopsis"`
+	Doc        string `json:"doc"`
+	DocMD      string `json:"docMarkdown"`
+	Deprecated string `json:"deprecated,omitempty"`
+
+	Files  []File  `json:"files"`
+	Consts []Value `json:"consts"`
+	Vars   []Value `json:"vars"`
+	Funcs  []Func  `json:"funcs"`
+	Types  []Type  `json:"types"`
+	Notes  []Note  `json:"notes"`
+
+	// Examples holds the examples of the package itself; examples of its
+	// functions, types and methods are found on them.
+	Examples []Example `json:"examples"`
+}
+
+// File is a source file of the package.
+type File struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// Position is the location of a declaration in the package's source files.
+type Position struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+	URL  string `json:"url"`
+}
+
+// Value is a block of constant or variable declarations.
+type Value struct {
+	Names      []string `json:"names"`
+	Doc        string   `json:"doc"`
+	DocMD      string   `json:"docMarkdown"`
+	Deprecated string   `json:"deprecated,omitempty"`
+	Platforms  string   `json:"platforms,omitempty"`
+	Decl       string   `json:"decl"`
+	Pos        Position `json:"pos"`
+}
+
+// Func is a function, or a method when Recv is set.
+type Func struct {
+	Name       string    `json:"name"`
+	Anchor     string    `json:"anchor"`
+	Recv       string    `json:"recv,omitempty"`
+	Doc        string    `json:"doc"`
+	DocMD      string    `json:"docMarkdown"`
+	Deprecated string    `json:"deprecated,omitempty"`
+	Platforms  string    `json:"platforms,omitempty"`
+	Decl       string    `json:"decl"`
+	Pos        Position  `json:"pos"`
+	Examples   []Example `json:"examples"`
+}
+
+// Method is a method of a Type. Its Recv is always set.
+type Method = Func
+
+// Type is a type declaration, along with the constants, variables, functions
+// and methods associated with it.
+type Type struct {
+	Name       string    `json:"name"`
+	Anchor     string    `json:"anchor"`
+	Kind       string    `json:"kind"`
+	Doc        string    `json:"doc"`
+	DocMD      string    `json:"docMarkdown"`
+	Deprecated string    `json:"deprecated,omitempty"`
+	Platforms  string    `json:"platforms,omitempty"`
+	Decl       string    `json:"decl"`
+	Pos        Position  `json:"pos"`
+	IsEnum     bool      `json:"isEnum"`
+	HasString  bool      `json:"hasString"`
+	Examples   []Example `json:"examples"`
+
+	// Fields holds the exported fields of a struct, or the methods of an
+	// interface.
+	Fields  []Field  `json:"fields"`
+	Consts  []Value  `json:"consts"`
+	Vars    []Value  `json:"vars"`
+	Funcs   []Func   `json:"funcs"`
+	Methods []Method `json:"methods"`
+}
+
+// Field is a field of a struct, or a method of an interface.
+type Field struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Tag      string `json:"tag,omitempty"`
+	Doc      string `json:"doc"`
+	DocMD    string `json:"docMarkdown"`
+	Embedded bool   `json:"embedded"`
+}
+
+// Example is a testable example from the package's test files. Suffix is the
+// lower case part of the example name following the symbol, e.g. "basic" for
+// ExampleFoo_basic.
+type Example struct {
+	Name   string `json:"name"`
+	Suffix string `json:"suffix,omitempty"`
+	Anchor string `json:"anchor"`
+	Doc    string `json:"doc"`
+	DocMD  string `json:"docMarkdown"`
+	Code   string `json:"code"`
+	Output string `json:"output"`
+}
+
+// Note is a marked comment, such as `BUG(uid): body`.
+type Note struct {
+	Marker string   `json:"marker"`
+	UID    string   `json:"uid"`
+	Body   string   `json:"body"`
+	BodyMD string   `json:"bodyMarkdown"`
+	Pos    Position `json:"pos"`
+}
+
+// checkModel validates the `-model` flag: the blocks of the built-in templates
+// are executed with godoc.PageInfo, so the template overrides must replace the
+// built-in template entirely.
+func checkModel(cfg *Cli, overrides string) error {
+	format, err := GetFormat(*cfg.Format)
+	if !*cfg.Model || err != nil || format.JSON {
+		return nil
+	}
+	if overrides != "" {
+		replaces, err := replacesTemplate(overrides)
+		if err != nil || replaces {
+			return err
+		}
+	}
+	return fmt.Errorf("-model requires a -template replacing the built-in template, whose blocks are executed with godoc.PageInfo")
+}
+
+// modelBuilder converts the godoc.PageInfo of a package into a Package.
+type modelBuilder struct {
+	info  *godoc.PageInfo
+	utils TemplateUtils
+	node  func(*godoc.PageInfo, interface{}) string
+}
+
+// NewPackage builds the documentation model of the package loaded into info.
+// Declarations are printed, and source URLs computed, the same way the
+// built-in templates do.
+func NewPackage(pres *godoc.Presentation, cfg *Cli, info *godoc.PageInfo) *Package {
+	b := modelBuilder{
+		info:  info,
+		utils: NewTemplateUtils(cfg),
+		node:  pres.FuncMap()["node"].(func(*godoc.PageInfo, interface{}) string),
+	}
+	b.utils.anchors.ReservePage(info)
+	b.utils.setSource(cfg, info)
+
+	return b.pkg()
+}
+
+func (b modelBuilder) pkg() *Package {
+	pdoc := b.info.PDoc
+	_, notice := splitDeprecation(pdoc.Doc)
+	pkg := &Package{
+		Version:    ModelVersion,
+		Name:       pdoc.Name,
+		ImportPath: pdoc.ImportPath,
+		IsCommand:  b.info.IsMain,
+		Synopsis:   doc.Synopsis(pdoc.Doc),
+		Doc:        pdoc.Doc,
+		DocMD:      b.utils.DocToMD(pdoc.Doc),
+		Deprecated: notice,
+		Files:      []File{},
+		Consts:     b.values(pdoc.Consts),
+		Vars:       b.values(pdoc.Vars),
+		Funcs:      b.funcs(pdoc.Funcs, ""),
+		Types:      []Type{},
+		Notes:      []Note{},
+	}
+
+	for _, filename := range pdoc.Filenames {
+		pkg.Files = append(pkg.Files, File{
+			Name: path.Base(filename),
+			URL:  b.utils.GetSourceFileURL(filename),
+		})
+	}
+
+	for _, t := range pdoc.Types {
+		pkg.Types = append(pkg.Types, b.typ(t))
+	}
+
+	pkg.Examples = b.examples("")
+
+	markers := make([]string, 0, len(b.info.Notes))
+	for marker := range b.info.Notes {
+		markers = append(markers, marker)
+	}
+	sort.Strings(markers)
+	for _, marker := range markers {
+		for _, n := range b.info.Notes[marker] {
+			pkg.Notes = append(pkg.Notes, Note{
+				Marker: marker,
+				UID:    n.UID,
+				Body:   n.Body,
+				BodyMD: b.utils.CommentToMD(n.Body),
+				Pos:    b.pos(n.Pos),
+			})
+		}
+	}
+
+	return pkg
+}
+
+// print formats the provided AST node, or declaration, as Go source.
+func (b modelBuilder) print(node interface{}) string {
+	return strings.TrimRight(b.node(b.info, node), " \n")
+}
+
+func (b modelBuilder) pos(p token.Pos) Position {
+	position := b.info.FSet.Position(p)
+	return Position{
+		File: path.Base(position.Filename),
+		Line: position.Line,
+		URL:  b.utils.getPosURL(b.info, p),
+	}
+}
+
+func (b modelBuilder) values(values []*doc.Value) []Value {
+	out := []Value{}
+	for _, v := range values {
+		_, notice := splitDeprecation(v.Doc)
+		out = append(out, Value{
+			Names:      v.Names,
+			Doc:        v.Doc,
+			DocMD:      b.utils.DocToMD(v.Doc),
+			Deprecated: notice,
+			Platforms:  symbolPlatforms(b.info.PDoc, v),
+			Decl:       b.print(v.Decl),
+			Pos:        b.pos(v.Decl.Pos()),
+		})
+	}
+	return out
+}
+
+func (b modelBuilder) funcs(funcs []*doc.Func, typeName string) []Func {
+	out := []Func{}
+	for _, f := range funcs {
+		_, notice := splitDeprecation(f.Doc)
+		anchor, egName := SymbolAnchor("", f.Name), f.Name
+		if typeName != "" && f.Recv != "" {
+			anchor = SymbolAnchor(typeName, f.Name)
+			egName = typeName + "_" + f.Name
+		}
+		out = append(out, Func{
+			Name:       f.Name,
+			Anchor:     anchor,
+			Recv:       f.Recv,
+			Doc:        f.Doc,
+			DocMD:      b.utils.DocToMD(f.Doc),
+			Deprecated: notice,
+			Platforms:  symbolPlatforms(b.info.PDoc, f),
+			Decl:       b.print(f.Decl),
+			Pos:        b.pos(f.Decl.Pos()),
+			Examples:   b.examples(egName),
+		})
+	}
+	return out
+}
+
+func (b modelBuilder) typ(t *doc.Type) Type {
+	_, notice := splitDeprecation(t.Doc)
+	typ := Type{
+		Name:       t.Name,
+		Anchor:     SymbolAnchor("", t.Name),
+		Kind:       "type",
+		Doc:        t.Doc,
+		DocMD:      b.utils.DocToMD(t.Doc),
+		Deprecated: notice,
+		Platforms:  symbolPlatforms(b.info.PDoc, t),
+		Decl:       b.print(t.Decl),
+		Pos:        b.pos(t.Decl.Pos()),
+		IsEnum:     b.utils.IsEnum(t),
+		HasString:  b.utils.HasStringer(t),
+		Examples:   b.examples(t.Name),
+		Fields:     []Field{},
+		Consts:     b.values(t.Consts),
+		Vars:       b.values(t.Vars),
+		Funcs:      b.funcs(t.Funcs, ""),
+		Methods:    b.funcs(t.Methods, t.Name),
+	}
+
+	for _, spec := range t.Decl.Specs {
+		ts, ok := spec.(*ast.TypeSpec)
+		if !ok || ts.Name.Name != t.Name {
+			continue
+		}
+		switch st := ts.Type.(type) {
+		case *ast.StructType:
+			typ.Kind = "struct"
+			typ.Fields = b.fields(st.Fields)
+		case *ast.InterfaceType:
+			typ.Kind = "interface"
+			typ.Fields = b.fields(st.Methods)
+		case *ast.FuncType:
+			typ.Kind = "func"
+		case *ast.MapType:
+			typ.Kind = "map"
+		case *ast.ArrayType:
+			typ.Kind = "slice"
+			if st.Len != nil {
+				typ.Kind = "array"
+			}
+		case *ast.ChanType:
+			typ.Kind = "chan"
+		case *ast.StarExpr:
+			typ.Kind = "pointer"
+		}
+		if ts.Assign.IsValid() {
+			typ.Kind = "alias"
+		}
+	}
+
+	return typ
+}
+
+func (b modelBuilder) fields(list *ast.FieldList) []Field {
+	out := []Field{}
+	if list == nil {
+		return out
+	}
+
+	for _, f := range list.List {
+		fieldDoc := f.Doc.Text()
+		if fieldDoc == "" {
+			fieldDoc = f.Comment.Text()
+		}
+		typ := b.print(f.Type)
+		tag := ""
+		if f.Tag != nil {
+			tag = f.Tag.Value
+		}
+
+		if len(f.Names) == 0 {
+			// embedded field, or embedded interface
+			name := typ
+			if i := strings.LastIndex(name, "."); i >= 0 {
+				name = name[i+1:]
+			}
+			out = append(out, Field{
+				Name:     strings.TrimPrefix(name, "*"),
+				Type:     typ,
+				Tag:      tag,
+				Doc:      fieldDoc,
+				DocMD:    b.utils.CommentToMD(fieldDoc),
+				Embedded: true,
+			})
+			continue
+		}
+		for _, name := range f.Names {
+			if !name.IsExported() {
+				continue
+			}
+			out = append(out, Field{
+				Name:  name.Name,
+				Type:  typ,
+				Tag:   tag,
+				Doc:   fieldDoc,
+				DocMD: b.utils.CommentToMD(fieldDoc),
+			})
+		}
+	}
+
+	return out
+}
+
+func (b modelBuilder) example(eg *doc.Example) Example {
+	code := exampleCode(b.print(&printer.CommentedNode{Node: eg.Code, Comments: eg.Comments}), eg.Output)
+
+	_, suffix := splitExampleName(eg.Name)
+
+	return Example{
+		Name:   eg.Name,
+		Suffix: suffix,
+		Anchor: "example_" + eg.Name,
+		Doc:    eg.Doc,
+		DocMD:  b.utils.CommentToMD(eg.Doc),
+		Code:   code,
+		Output: eg.Output,
+	}
+}
+
+// examples returns the examples of the symbol called name, where methods are
+// named Type_Method and the package itself is named "".
+func (b modelBuilder) examples(name string) []Example {
+	out := []Example{}
+	for _, eg := range b.info.Examples {
+		if egName, _ := splitExampleName(eg.Name); egName == name {
+			out = append(out, b.example(eg))
+		}
+	}
+	return out
+}
+
+// splitExampleName splits an example name into the name of the symbol it
+// documents and its suffix, following the conventions of the testing package.
+//
+// Original Source https://github.com/golang/tools/blob/master/godoc/godoc.go#L700
+func splitExampleName(s string) (name, suffix string) {
+	i := strings.LastIndex(s, "_")
+	if 0 <= i && i < len(s)-1 && !startsWithUppercase(s[i+1:]) {
+		name = s[:i]
+		suffix = s[i+1:]
+		return
+	}
+	return s, ""
+}
+
+func startsWithUppercase(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return unicode.IsUpper(r)
+}
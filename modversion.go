@@ -0,0 +1,239 @@
+package godoc2md
+
+import (
+	"archive/zip"
+	"fmt"
+	"go/build"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/tools/godoc"
+	"golang.org/x/tools/godoc/vfs"
+	"golang.org/x/tools/godoc/vfs/zipfs"
+)
+
+// ModuleVersion is a released version of a module, read from the module cache
+// or from a module zip file rather than from a working tree.
+type ModuleVersion struct {
+	// Module is the module, whose Dir is empty. Its RepoDir is unknown, and
+	// empty too.
+	Module
+	Version string
+
+	// fs holds the files of the module below root.
+	fs   vfs.FileSystem
+	root string
+}
+
+// pseudoVersionRx matches the revision suffix of a pseudo-version, as in
+// "v0.0.0-20181011021141-0e57ebad1d6b".
+var pseudoVersionRx = regexp.MustCompile(`[.-]\d{14}-([0-9a-f]{12})(\+incompatible)?$`)
+
+// ref returns the revision of the repository the version was released from,
+// which its source links are pinned to: its tag, or the commit of a
+// pseudo-version.
+func (mv *ModuleVersion) ref() string {
+	if m := pseudoVersionRx.FindStringSubmatch(mv.Version); m != nil {
+		return m[1]
+	}
+	return strings.TrimSuffix(mv.Version, "+incompatible")
+}
+
+// bind mounts the directory of the package at importPath on the virtual
+// "/target" directory of fs.
+func (mv *ModuleVersion) bind(fs vfs.NameSpace, importPath string) {
+	rel := strings.TrimPrefix(strings.TrimPrefix(importPath, mv.Path), "/")
+	fs.Bind(target, mv.fs, path.Join(mv.root, rel), vfs.BindReplace)
+}
+
+// contains reports whether the package at importPath belongs to the module.
+func (mv *ModuleVersion) contains(importPath string) bool {
+	return importPath == mv.Path || strings.HasPrefix(importPath, mv.Path+"/")
+}
+
+// splitVersion splits a package argument of the form "importPath@version".
+// The version is empty if arg has none.
+func splitVersion(arg string) (importPath, version string) {
+	if i := strings.LastIndex(arg, "@"); i >= 0 {
+		return arg[:i], arg[i+1:]
+	}
+	return arg, ""
+}
+
+// isVersioned reports whether the package argument arg names a package of a
+// module version: one given with a version, or any with the `-modzip` flag.
+func isVersioned(cfg *Cli, arg string) bool {
+	return strings.Contains(arg, "@") || *cfg.ModZip != ""
+}
+
+// moduleVersion returns the module version the versioned package argument
+// arg belongs to, opening it on first use.
+func (cfg *Cli) moduleVersion(arg string) (*ModuleVersion, error) {
+	importPath, version := splitVersion(arg)
+	for _, mv := range cfg.Versions {
+		if mv.contains(importPath) && (version == "" || version == mv.Version) {
+			return mv, nil
+		}
+	}
+
+	mv, err := openModuleVersion(*cfg.ModZip, importPath, version)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Versions = append(cfg.Versions, mv)
+	return mv, nil
+}
+
+// versionOf returns the opened module version providing the package at
+// importPath, or nil.
+func (cfg *Cli) versionOf(importPath string) *ModuleVersion {
+	var found *ModuleVersion
+	for _, mv := range cfg.Versions {
+		if mv.contains(importPath) && (found == nil || len(mv.Path) > len(found.Path)) {
+			found = mv
+		}
+	}
+	return found
+}
+
+// openModuleVersion opens the version of the module providing the package at
+// importPath, from the module zip file zipFile if set, or from the module
+// cache. Nothing is downloaded.
+func openModuleVersion(zipFile, importPath, version string) (*ModuleVersion, error) {
+	if zipFile != "" {
+		mv, err := openModuleZip(zipFile)
+		if err != nil {
+			return nil, err
+		}
+		if !mv.contains(importPath) {
+			return nil, fmt.Errorf("%s: package %s is not in module %s", zipFile, importPath, mv.Path)
+		}
+		if version != "" && version != mv.Version {
+			return nil, fmt.Errorf("%s: module %s is at version %s, not %s", zipFile, mv.Path, mv.Version, version)
+		}
+		return mv, nil
+	}
+
+	if version == "" {
+		return nil, fmt.Errorf("%s: no version", importPath)
+	}
+	cache := modCacheDir()
+	escVersion := escapeModulePath(version)
+
+	// the module is the longest prefix of importPath in the cache
+	for modPath := importPath; modPath != "." && modPath != "/"; modPath = path.Dir(modPath) {
+		escPath := filepath.FromSlash(escapeModulePath(modPath))
+
+		dir := filepath.Join(cache, escPath+"@"+escVersion)
+		if fi, err := os.Stat(dir); err == nil && fi.IsDir() {
+			return &ModuleVersion{
+				Module:  Module{Path: modPath},
+				Version: version,
+				fs:      vfs.OS(dir),
+				root:    "/",
+			}, nil
+		}
+
+		zipFile := filepath.Join(cache, "cache", "download", escPath, "@v", escVersion+".zip")
+		if _, err := os.Stat(zipFile); err == nil {
+			return openModuleZip(zipFile)
+		}
+	}
+
+	return nil, fmt.Errorf("%s@%s: not found in module cache %s", importPath, version, cache)
+}
+
+// openModuleZip opens the module zip file filename, whose files are all
+// below a "module@version" directory.
+func openModuleZip(filename string) (*ModuleVersion, error) {
+	rc, err := zip.OpenReader(filename)
+	if err != nil {
+		return nil, err
+	}
+	root := ""
+	for _, f := range rc.File {
+		if at := strings.Index(f.Name, "@"); at >= 0 {
+			if end := strings.Index(f.Name[at:], "/"); end >= 0 {
+				root = f.Name[:at+end]
+				break
+			}
+		}
+	}
+	escPath, escVersion, ok := strings.Cut(root, "@")
+	if !ok {
+		rc.Close()
+		return nil, fmt.Errorf("%s: not a module zip, no module@version directory", filename)
+	}
+
+	return &ModuleVersion{
+		Module:  Module{Path: unescapeModulePath(escPath)},
+		Version: unescapeModulePath(escVersion),
+		fs:      zipfs.New(rc, filepath.Base(filename)),
+		root:    "/" + root,
+	}, nil
+}
+
+// modCacheDir returns the module cache directory: GOMODCACHE, or the pkg/mod
+// directory of the first GOPATH entry.
+func modCacheDir() string {
+	if dir := os.Getenv("GOMODCACHE"); dir != "" {
+		return dir
+	}
+	return filepath.Join(filepath.SplitList(build.Default.GOPATH)[0], "pkg", "mod")
+}
+
+// escapeModulePath escapes a module path or version the way the module cache
+// does, replacing upper case letters with an exclamation mark followed by the
+// lower case letter.
+func escapeModulePath(p string) string {
+	var b strings.Builder
+	for _, r := range p {
+		if unicode.IsUpper(r) {
+			b.WriteByte('!')
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// unescapeModulePath reverses escapeModulePath.
+func unescapeModulePath(p string) string {
+	var b strings.Builder
+	upper := false
+	for _, r := range p {
+		if r == '!' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// getVersionPageInfo loads the package documentation for the package named by
+// args[0], a package of the module version mv, like GetPageInfo does for
+// directories.
+func getVersionPageInfo(fs vfs.NameSpace, pres *godoc.Presentation, mv *ModuleVersion, args []string) (*godoc.PageInfo, error) {
+	importPath, _ := splitVersion(args[0])
+	mv.bind(fs, importPath)
+
+	return getTargetPageInfo(pres, append([]string{importPath}, args[1:]...))
+}
+
+// versionOfPage returns the module version the package documented by info
+// was loaded from, or nil.
+func versionOfPage(cfg *Cli, info *godoc.PageInfo) *ModuleVersion {
+	if info.PDoc == nil {
+		return nil
+	}
+	return cfg.versionOf(info.PDoc.ImportPath)
+}
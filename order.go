@@ -0,0 +1,312 @@
+package godoc2md
+
+import (
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/parser"
+	"go/token"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/godoc"
+	"golang.org/x/tools/godoc/vfs"
+)
+
+// The values of the `-order` flag.
+const (
+	// orderName keeps the alphabetical order of go/doc.
+	orderName = "name"
+	// orderSource orders symbols by source file and position.
+	orderSource = "source"
+	// orderFile orders symbols like orderSource, and groups the functions
+	// and types under a heading per source file.
+	orderFile = "file"
+	// orderSection orders symbols like orderSource, and groups the
+	// functions and types under a heading per `// Section: Title` comment.
+	orderSection = "section"
+)
+
+// sectionRx matches the comment lines starting a section, as in
+// `// Section: Title`. The section lasts until the next one of the file.
+var sectionRx = regexp.MustCompile(`^Section:\s*(.*\S)\s*$`)
+
+// SymbolGroup is a group of package level functions and types, as rendered
+// by the "groups" template block.
+type SymbolGroup struct {
+	// Title is the source file, or the section, of the symbols. It is empty
+	// for the symbols preceding the first section of their file.
+	Title string
+	// Anchor is the anchor id of the group heading.
+	Anchor string
+	Funcs  []*doc.Func
+	Types  []*doc.Type
+}
+
+// sectionMarker is a `// Section: Title` comment.
+type sectionMarker struct {
+	line  int
+	title string
+}
+
+// symbolOrder assigns the symbols of a package to their group.
+type symbolOrder struct {
+	order   string
+	fset    *token.FileSet
+	markers map[string][]sectionMarker
+	// groups are the group titles, in order.
+	groups []string
+}
+
+func newSymbolOrder(info *godoc.PageInfo, order string) (*symbolOrder, error) {
+	switch order {
+	case orderName, orderSource, orderFile, orderSection:
+	default:
+		return nil, fmt.Errorf("unknown order %q, must be one of: %s, %s, %s, %s", order, orderName, orderSource, orderFile, orderSection)
+	}
+
+	o := &symbolOrder{order: order, fset: info.FSet, markers: map[string][]sectionMarker{}}
+	if info.PDoc == nil {
+		return o, nil
+	}
+	seen := map[string]bool{}
+	addGroup := func(title string) {
+		if !seen[title] {
+			seen[title] = true
+			o.groups = append(o.groups, title)
+		}
+	}
+	switch order {
+	case orderFile:
+		filenames := append([]string(nil), info.PDoc.Filenames...)
+		sort.Strings(filenames)
+		for _, filename := range filenames {
+			addGroup(filepath.Base(filename))
+		}
+	case orderSection:
+		addGroup("")
+	}
+	if order != orderSection {
+		return o, nil
+	}
+
+	filenames := make([]string, 0, len(info.PAst))
+	for filename := range info.PAst {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+	for _, filename := range filenames {
+		for _, group := range info.PAst[filename].Comments {
+			for _, line := range strings.Split(group.Text(), "\n") {
+				if m := sectionRx.FindStringSubmatch(line); m != nil {
+					marker := sectionMarker{line: o.fset.Position(group.Pos()).Line, title: m[1]}
+					o.markers[filename] = append(o.markers[filename], marker)
+					addGroup(m[1])
+				}
+			}
+		}
+	}
+
+	return o, nil
+}
+
+func (o *symbolOrder) filename(pos token.Pos) string {
+	return filepath.Base(o.fset.Position(pos).Filename)
+}
+
+// group returns the title of the group of the symbol declared at pos.
+func (o *symbolOrder) group(pos token.Pos) string {
+	switch o.order {
+	case orderFile:
+		return o.filename(pos)
+	case orderSection:
+		title := ""
+		position := o.fset.Position(pos)
+		for _, marker := range o.markers[position.Filename] {
+			if marker.line < position.Line {
+				title = marker.title
+			}
+		}
+		return title
+	}
+	return ""
+}
+
+// less orders the symbols declared at a and b by group, and by position.
+func (o *symbolOrder) less(a, b token.Pos) bool {
+	if ga, gb := o.groupIndex(a), o.groupIndex(b); ga != gb {
+		return ga < gb
+	}
+	pa, pb := o.fset.Position(a), o.fset.Position(b)
+	if pa.Filename != pb.Filename {
+		return pa.Filename < pb.Filename
+	}
+	return pa.Offset < pb.Offset
+}
+
+func (o *symbolOrder) groupIndex(pos token.Pos) int {
+	title := o.group(pos)
+	for i, group := range o.groups {
+		if group == title {
+			return i
+		}
+	}
+	return len(o.groups)
+}
+
+// orderSymbols reorders the symbols of the package documented by info as
+// configured by the `-order` flag. Section comments that ended up in a doc
+// comment are removed from it.
+//
+// godoc only keeps the syntax trees of the package files in source mode, so
+// the section order parses them again from fs, into info.PAst, for their
+// comments.
+func orderSymbols(fs vfs.NameSpace, info *godoc.PageInfo, order string) error {
+	if order == orderSection && info.PDoc != nil && info.PAst == nil {
+		if err := parseFiles(fs, info); err != nil {
+			return err
+		}
+	}
+
+	o, err := newSymbolOrder(info, order)
+	if err != nil || order == orderName || info.PDoc == nil {
+		return err
+	}
+
+	pkg := info.PDoc
+	o.values(pkg.Consts)
+	o.values(pkg.Vars)
+	o.funcs(pkg.Funcs)
+	sort.SliceStable(pkg.Types, func(i, j int) bool {
+		return o.less(pkg.Types[i].Decl.Pos(), pkg.Types[j].Decl.Pos())
+	})
+	for _, t := range pkg.Types {
+		t.Doc = stripSections(t.Doc)
+		o.values(t.Consts)
+		o.values(t.Vars)
+		o.funcs(t.Funcs)
+		o.funcs(t.Methods)
+	}
+	return nil
+}
+
+func (o *symbolOrder) values(values []*doc.Value) {
+	for _, v := range values {
+		v.Doc = stripSections(v.Doc)
+	}
+	sort.SliceStable(values, func(i, j int) bool {
+		return o.less(values[i].Decl.Pos(), values[j].Decl.Pos())
+	})
+}
+
+func (o *symbolOrder) funcs(funcs []*doc.Func) {
+	for _, f := range funcs {
+		f.Doc = stripSections(f.Doc)
+	}
+	sort.SliceStable(funcs, func(i, j int) bool {
+		return o.less(funcs[i].Decl.Pos(), funcs[j].Decl.Pos())
+	})
+}
+
+// stripSections removes the lines of doc comment text starting a section.
+func stripSections(text string) string {
+	if !strings.Contains(text, "Section:") {
+		return text
+	}
+	lines := strings.SplitAfter(text, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if !sectionRx.MatchString(strings.TrimSuffix(line, "\n")) {
+			kept = append(kept, line)
+		}
+	}
+	return strings.TrimLeft(strings.Join(kept, ""), "\n")
+}
+
+// parseFiles parses the files of the package documented by info, comments
+// included, into info.PAst. The files are looked up by the names godoc gave
+// them in info.FSet, which are paths of fs, unlike the import path based
+// names of PDoc.Filenames.
+func parseFiles(fs vfs.NameSpace, info *godoc.PageInfo) error {
+	pkgFiles := map[string]bool{}
+	for _, filename := range info.PDoc.Filenames {
+		pkgFiles[path.Base(filename)] = true
+	}
+	var filenames []string
+	info.FSet.Iterate(func(f *token.File) bool {
+		if pkgFiles[path.Base(f.Name())] {
+			filenames = append(filenames, f.Name())
+		}
+		return true
+	})
+
+	info.PAst = map[string]*ast.File{}
+	for _, filename := range filenames {
+		src, err := vfs.ReadFile(fs, filename)
+		if err != nil {
+			return err
+		}
+		file, err := parser.ParseFile(info.FSet, filename, src, parser.ParseComments)
+		if err != nil {
+			return err
+		}
+		info.PAst[filename] = file
+	}
+	return nil
+}
+
+// symbolGroups returns the groups of the package level functions and types
+// of the package documented by info, for the file and section orders, and
+// nil otherwise. The anchors of the groups are unique within the page, as
+// suffixed by forge.
+func symbolGroups(info *godoc.PageInfo, order string, forge Forge) []SymbolGroup {
+	if (order != orderFile && order != orderSection) || info.PDoc == nil {
+		return nil
+	}
+	o, err := newSymbolOrder(info, order)
+	if err != nil {
+		return nil
+	}
+
+	byTitle := map[string]*SymbolGroup{}
+	group := func(pos token.Pos) *SymbolGroup {
+		title := o.group(pos)
+		g, ok := byTitle[title]
+		if !ok {
+			g = &SymbolGroup{Title: title}
+			byTitle[title] = g
+		}
+		return g
+	}
+	for _, f := range info.PDoc.Funcs {
+		g := group(f.Decl.Pos())
+		g.Funcs = append(g.Funcs, f)
+	}
+	for _, t := range info.PDoc.Types {
+		g := group(t.Decl.Pos())
+		g.Types = append(g.Types, t)
+	}
+
+	anchors := NewAnchors(forge)
+	anchors.ReservePage(info)
+	var groups []SymbolGroup
+	for _, title := range o.groups {
+		if g, ok := byTitle[title]; ok {
+			if title != "" {
+				g.Anchor = anchors.prefixed("pkg-"+order+"-", title)
+			}
+			groups = append(groups, *g)
+		}
+	}
+	return groups
+}
+
+// SymbolGroups returns the groups of the package level functions and types
+// of the package documented by info, as configured by the `-order` flag, or
+// nil if they are not grouped.
+func (t TemplateUtils) SymbolGroups(info *godoc.PageInfo) []SymbolGroup {
+	return symbolGroups(info, t.order, t.forge)
+}
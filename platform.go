@@ -0,0 +1,460 @@
+package godoc2md
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/doc"
+	"go/token"
+	"path"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/godoc"
+)
+
+// Platform is a target operating system and architecture, as in
+// "linux/amd64".
+type Platform struct {
+	GOOS   string
+	GOARCH string
+}
+
+func (p Platform) String() string {
+	return p.GOOS + "/" + p.GOARCH
+}
+
+// ParsePlatforms parses a comma separated list of platforms, as given to the
+// `-platforms` flag.
+func ParsePlatforms(list string) ([]Platform, error) {
+	var platforms []Platform
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		goos, goarch, ok := strings.Cut(item, "/")
+		if !ok || goos == "" || goarch == "" {
+			return nil, fmt.Errorf("invalid platform %q, must be of the form goos/goarch", item)
+		}
+		platforms = append(platforms, Platform{GOOS: goos, GOARCH: goarch})
+	}
+	return platforms, nil
+}
+
+// osNames are the display names of the operating systems in availability
+// notes. Others are shown by GOOS.
+var osNames = map[string]string{
+	"aix":       "AIX",
+	"android":   "Android",
+	"darwin":    "macOS",
+	"dragonfly": "DragonFly BSD",
+	"freebsd":   "FreeBSD",
+	"illumos":   "illumos",
+	"ios":       "iOS",
+	"js":        "JavaScript",
+	"linux":     "Linux",
+	"netbsd":    "NetBSD",
+	"openbsd":   "OpenBSD",
+	"plan9":     "Plan 9",
+	"solaris":   "Solaris",
+	"wasip1":    "WASI",
+	"windows":   "Windows",
+}
+
+// configureBuild applies the `-tags`, `-goos` and `-goarch` flags to the
+// default build context, which godoc loads packages with.
+func configureBuild(cfg *Cli) {
+	if *cfg.Tags != "" {
+		build.Default.BuildTags = strings.FieldsFunc(*cfg.Tags, func(r rune) bool {
+			return r == ',' || r == ' '
+		})
+	}
+	if *cfg.GOOS != "" {
+		build.Default.GOOS = *cfg.GOOS
+	}
+	if *cfg.GOARCH != "" {
+		build.Default.GOARCH = *cfg.GOARCH
+	}
+	if build.Default.GOOS != runtime.GOOS || build.Default.GOARCH != runtime.GOARCH {
+		// cgo is off when cross-compiling, unless asked for
+		build.Default.CgoEnabled = false
+	}
+}
+
+// pageLoader loads the package documentation for the package named by
+// args[0], as GetPageInfo does.
+type pageLoader func(args []string) (*godoc.PageInfo, error)
+
+// platformMu serializes the loads under the build context of a platform,
+// which is set up in the default build context godoc reads.
+var platformMu sync.Mutex
+
+// getPlatformPageInfo loads the package named by args[0] with load, under the
+// build context of platform p.
+func getPlatformPageInfo(load pageLoader, args []string, p Platform) (*godoc.PageInfo, error) {
+	platformMu.Lock()
+	defer platformMu.Unlock()
+
+	saved := build.Default
+	defer func() { build.Default = saved }()
+
+	build.Default.GOOS = p.GOOS
+	build.Default.GOARCH = p.GOARCH
+	build.Default.CgoEnabled = false
+
+	return load(args)
+}
+
+// getMultiPlatformPageInfo loads the package named by args[0] with load under
+// every platform of the `-platforms` flag. The package is documented with the
+// symbols of all the platforms, and those missing from some of them are
+// noted with the platforms they exist on, as symbolPlatforms returns.
+func getMultiPlatformPageInfo(load pageLoader, cfg *Cli, args []string) (*godoc.PageInfo, error) {
+	platforms, err := ParsePlatforms(*cfg.Platforms)
+	if err != nil {
+		return nil, err
+	}
+	if len(platforms) == 0 {
+		return load(args)
+	}
+
+	var info *godoc.PageInfo
+	available := map[string][]Platform{}
+	for _, p := range platforms {
+		pinfo, err := getPlatformPageInfo(load, args, p)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", p, err)
+		}
+		switch {
+		case info == nil || !onPlatform(info) && onPlatform(pinfo):
+			// the package is documented as declared on the first
+			// platform it exists on
+			info = pinfo
+		case onPlatform(pinfo):
+			mergePlatformSymbols(info, pinfo)
+		}
+		if !onPlatform(pinfo) {
+			continue
+		}
+		for _, name := range symbolNames(pinfo.PDoc) {
+			available[name] = append(available[name], p)
+		}
+	}
+
+	if info.PDoc != nil {
+		recordPlatforms(args[0], info.PDoc, platforms, available)
+	}
+	return info, nil
+}
+
+// onPlatform reports whether the package documented by info has files for
+// the platform it was loaded for. godoc documents the files the build ignores
+// as a command otherwise.
+func onPlatform(info *godoc.PageInfo) bool {
+	return info.PDoc != nil && (!info.IsMain || info.PDoc.Name == "main")
+}
+
+// mergePlatformSymbols adds the symbols of the package documented by other,
+// loaded for another platform, that are missing from info to info. Their
+// declarations are moved to the file set of info.
+func mergePlatformSymbols(info, other *godoc.PageInfo) {
+	pkg := info.PDoc
+	exists := map[string]bool{}
+	for _, name := range symbolNames(pkg) {
+		exists[name] = true
+	}
+	r := &posRebaser{
+		to:    info.FSet,
+		from:  other.FSet,
+		files: map[*token.File]*token.File{},
+		moved: map[string]bool{},
+		seen:  map[uintptr]bool{},
+	}
+
+	values := func(dst *[]*doc.Value, values []*doc.Value) {
+		for _, v := range values {
+			missing := true
+			for _, name := range v.Names {
+				missing = missing && !exists[name]
+			}
+			if missing {
+				r.rebase(v)
+				*dst = append(*dst, v)
+			}
+		}
+	}
+	funcs := func(dst *[]*doc.Func, prefix string, funcs []*doc.Func) {
+		for _, f := range funcs {
+			if !exists[prefix+f.Name] {
+				r.rebase(f)
+				*dst = append(*dst, f)
+			}
+		}
+		sort.Slice(*dst, func(i, j int) bool { return (*dst)[i].Name < (*dst)[j].Name })
+	}
+
+	values(&pkg.Consts, other.PDoc.Consts)
+	values(&pkg.Vars, other.PDoc.Vars)
+	funcs(&pkg.Funcs, "", other.PDoc.Funcs)
+	for _, ot := range other.PDoc.Types {
+		var t *doc.Type
+		for _, pt := range pkg.Types {
+			if pt.Name == ot.Name {
+				t = pt
+				break
+			}
+		}
+		if t == nil {
+			r.rebase(ot)
+			pkg.Types = append(pkg.Types, ot)
+			continue
+		}
+		values(&t.Consts, ot.Consts)
+		values(&t.Vars, ot.Vars)
+		funcs(&t.Funcs, "", ot.Funcs)
+		funcs(&t.Methods, t.Name+".", ot.Methods)
+	}
+	sort.Slice(pkg.Types, func(i, j int) bool { return pkg.Types[i].Name < pkg.Types[j].Name })
+
+	// list the files the added symbols are declared in
+	for _, filename := range other.PDoc.Filenames {
+		if !r.moved[path.Base(filename)] {
+			continue
+		}
+		found := false
+		for _, f := range pkg.Filenames {
+			found = found || f == filename
+		}
+		if !found {
+			pkg.Filenames = append(pkg.Filenames, filename)
+		}
+	}
+	sort.Strings(pkg.Filenames)
+}
+
+// posRebaser moves syntax trees from the file set they were parsed into to
+// another one, by adding their files to it and shifting their positions.
+type posRebaser struct {
+	to, from *token.FileSet
+	// files maps the files of from to their copy in to.
+	files map[*token.File]*token.File
+	// moved records the base names of the files copied.
+	moved map[string]bool
+	seen  map[uintptr]bool
+}
+
+var (
+	posType    = reflect.TypeOf(token.NoPos)
+	objectType = reflect.TypeOf((*ast.Object)(nil))
+	scopeType  = reflect.TypeOf((*ast.Scope)(nil))
+)
+
+// rebase shifts the positions of the syntax trees reachable from node, such
+// as a *doc.Func, to the file set to. The objects and scopes of identifiers,
+// which point back to declarations, are skipped.
+func (r *posRebaser) rebase(node interface{}) {
+	r.walk(reflect.ValueOf(node))
+}
+
+func (r *posRebaser) walk(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || v.Type() == objectType || v.Type() == scopeType || r.seen[v.Pointer()] {
+			return
+		}
+		r.seen[v.Pointer()] = true
+		r.walk(v.Elem())
+	case reflect.Interface:
+		if !v.IsNil() {
+			r.walk(v.Elem())
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			r.walk(v.Index(i))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Field(i)
+			if field.Type() == posType && field.CanSet() {
+				field.SetInt(int64(r.pos(token.Pos(field.Int()))))
+				continue
+			}
+			r.walk(field)
+		}
+	}
+}
+
+// pos returns the position of to matching the position p of from.
+func (r *posRebaser) pos(p token.Pos) token.Pos {
+	f := r.from.File(p)
+	if f == nil {
+		return p
+	}
+	nf, ok := r.files[f]
+	if !ok {
+		nf = r.to.AddFile(f.Name(), -1, f.Size())
+		lines := make([]int, f.LineCount())
+		for i := range lines {
+			lines[i] = f.Offset(f.LineStart(i + 1))
+		}
+		nf.SetLines(lines)
+		r.files[f] = nf
+		r.moved[path.Base(f.Name())] = true
+	}
+	return nf.Pos(f.Offset(p))
+}
+
+// symbolNames returns the names of the symbols of pkg, with methods qualified
+// by their type, as in "Type.Method".
+func symbolNames(pkg *doc.Package) []string {
+	var names []string
+	values := func(values []*doc.Value) {
+		for _, v := range values {
+			names = append(names, v.Names...)
+		}
+	}
+	funcs := func(prefix string, funcs []*doc.Func) {
+		for _, f := range funcs {
+			names = append(names, prefix+f.Name)
+		}
+	}
+
+	values(pkg.Consts)
+	values(pkg.Vars)
+	funcs("", pkg.Funcs)
+	for _, t := range pkg.Types {
+		names = append(names, t.Name)
+		values(t.Consts)
+		values(t.Vars)
+		funcs("", t.Funcs)
+		funcs(t.Name+".", t.Methods)
+	}
+	return names
+}
+
+// platformNotes holds the availability notes of the symbols of the packages
+// loaded under several platforms, by symbol name. Only the last package loaded
+// for an argument is kept, so that previews don't accumulate them.
+var platformNotes = struct {
+	sync.Mutex
+	pkgs map[*doc.Package]map[string]string
+	last map[string]*doc.Package
+}{
+	pkgs: map[*doc.Package]map[string]string{},
+	last: map[string]*doc.Package{},
+}
+
+// recordPlatforms records the availability notes of the symbols of pkg, loaded
+// for arg, that don't exist on all platforms, as recorded by available.
+func recordPlatforms(arg string, pkg *doc.Package, platforms []Platform, available map[string][]Platform) {
+	notes := map[string]string{}
+	for _, name := range symbolNames(pkg) {
+		if on := available[name]; len(on) > 0 && len(on) < len(platforms) {
+			notes[name] = platformsNote(platforms, on)
+		}
+	}
+
+	platformNotes.Lock()
+	defer platformNotes.Unlock()
+	delete(platformNotes.pkgs, platformNotes.last[arg])
+	platformNotes.last[arg] = pkg
+	if len(notes) > 0 {
+		platformNotes.pkgs[pkg] = notes
+	}
+}
+
+// symbolPlatforms returns the availability note of symbol, a *doc.Value,
+// *doc.Type or *doc.Func of pkg, or "" if it exists on all platforms. The
+// names of a value group are noted individually unless they share the note.
+func symbolPlatforms(pkg *doc.Package, symbol interface{}) string {
+	var names []string
+	switch s := symbol.(type) {
+	case *doc.Value:
+		names = s.Names
+	case *doc.Type:
+		names = []string{s.Name}
+	case *doc.Func:
+		name := s.Name
+		if s.Recv != "" {
+			name = recvTypeName(s.Recv) + "." + name
+		}
+		names = []string{name}
+	}
+
+	platformNotes.Lock()
+	notes := platformNotes.pkgs[pkg]
+	platformNotes.Unlock()
+
+	var noted []string
+	shared := true
+	for _, name := range names {
+		note := notes[name]
+		shared = shared && note == notes[names[0]]
+		if note != "" {
+			noted = append(noted, name+": "+note)
+		}
+	}
+	switch {
+	case len(noted) == 0:
+		return ""
+	case shared:
+		return notes[names[0]]
+	}
+	return strings.Join(noted, " ")
+}
+
+// recvTypeName returns the name of the type of the receiver recv, as in "T"
+// for "*T[P]".
+func recvTypeName(recv string) string {
+	recv = strings.TrimPrefix(recv, "*")
+	if i := strings.IndexByte(recv, '['); i >= 0 {
+		recv = recv[:i]
+	}
+	return recv
+}
+
+// platformsNote returns the availability note of a symbol existing on the
+// platforms on, out of all platforms, as in "Linux and macOS only.". An
+// operating system is named on its own if the symbol exists on all of its
+// architectures.
+func platformsNote(platforms, on []Platform) string {
+	archs := map[string]int{}
+	for _, p := range platforms {
+		archs[p.GOOS]++
+	}
+	found := map[string]int{}
+	for _, p := range on {
+		found[p.GOOS]++
+	}
+
+	var names []string
+	seen := map[string]bool{}
+	for _, p := range on {
+		if found[p.GOOS] == archs[p.GOOS] {
+			if !seen[p.GOOS] {
+				seen[p.GOOS] = true
+				names = append(names, osName(p.GOOS))
+			}
+			continue
+		}
+		names = append(names, p.String())
+	}
+	sort.Strings(names)
+
+	list := names[0]
+	if n := len(names); n > 1 {
+		list = strings.Join(names[:n-1], ", ") + " and " + names[n-1]
+	}
+	return list + " only."
+}
+
+func osName(goos string) string {
+	if name, ok := osNames[goos]; ok {
+		return name
+	}
+	return goos
+}
@@ -6,10 +6,14 @@ import (
 	"log"
 	"os"
 	"path"
+	"path/filepath"
+	"regexp"
 	"strings"
 	"text/template"
+	"text/template/parse"
 
 	"golang.org/x/tools/godoc"
+	"golang.org/x/tools/godoc/vfs"
 )
 
 var (
@@ -54,31 +58,207 @@ func NewPresentation(corpus *godoc.Corpus, config *Cli) *godoc.Presentation {
 	pres.SrcMode = false
 	pres.HTMLMode = false
 
+	if *config.NotesRx != "" {
+		notesRx, err := regexp.Compile(*config.NotesRx)
+		if err != nil {
+			log.Fatalf("error parsing notes expression: %v", err)
+		}
+		pres.NotesRx = notesRx
+	}
+
 	sl := &sourceLinker{HashFormat: *config.SrcLinkHashFormat}
 	pres.URLForSrc = sl.source
 	pres.URLForSrcPos = sl.sourcePosition
 
-	templateText := pkgTemplate
-	if *config.AltPkgTemplate != "" {
-		templateName = *config.AltPkgTemplate
-		buf, err := os.ReadFile(templateName)
-		if err != nil {
-			log.Fatal(err)
-		}
-		templateText = string(buf)
+	docTemplate, err := NewTemplate(pres, config, *config.AltPkgTemplate)
+	if err != nil {
+		log.Fatalf("error parsing template: %v", err)
+	}
+	pres.PackageText = docTemplate
+
+	return pres
+}
+
+// workerPresentation returns a copy of the name space fs, and a presentation
+// of the copy sharing the template of pres, for a worker loading packages
+// concurrently with others, as packages are bound on the "/target" directory
+// of the name space they are loaded from.
+func workerPresentation(fs vfs.NameSpace, pres *godoc.Presentation, config *Cli) (vfs.NameSpace, *godoc.Presentation) {
+	ns := vfs.NameSpace{}
+	for mount, fss := range fs {
+		ns[mount] = fss
+	}
+
+	corpus := godoc.NewCorpus(ns)
+	corpus.Verbose = pres.Corpus.Verbose
+	wpres := NewPresentation(corpus, config)
+	wpres.PackageText = pres.PackageText
+	return ns, wpres
+}
+
+// NewTemplate parses the built-in template of the configured format with the
+// godoc and godoc2md template funcs, and layers the template file or directory
+// named by overrides on top of it, if not empty. Overrides of formats without
+// blocks must replace the built-in template.
+func NewTemplate(pres *godoc.Presentation, config *Cli, overrides string) (*template.Template, error) {
+	format, err := GetFormat(*config.Format)
+	if err != nil {
+		return nil, err
 	}
+	if _, err := GetForge(*config.Forge); err != nil {
+		return nil, err
+	}
+
 	docTemplate := template.New(templateName)
 	docTemplate.Funcs(pres.FuncMap())
 
 	utilFuncs := NewTemplateUtils(config)
 	docTemplate.Funcs(utilFuncs.Methods())
 
-	var err error
-	pres.PackageText, err = docTemplate.Parse(templateText)
+	if _, err := docTemplate.Parse(format.Template); err != nil {
+		return nil, err
+	}
+
+	if overrides != "" {
+		if !format.Blocks && !format.JSON {
+			replaces, err := replacesTemplate(overrides)
+			if err != nil {
+				return nil, err
+			}
+			if !replaces {
+				return nil, fmt.Errorf("the %s template has no blocks to override, -template must replace it entirely", format.Name)
+			}
+		}
+		if err := parseTemplateOverrides(docTemplate, overrides); err != nil {
+			return nil, err
+		}
+	}
+
+	return docTemplate, nil
+}
 
+// pageTemplate returns a copy of the package template of pres, whose funcs
+// hand out the heading anchors of the page documenting info. The page is
+// written to the directory dir, relative to the output directory. With the
+// split layout, page names the page being rendered, or is empty for the
+// package page.
+func pageTemplate(pres *godoc.Presentation, config *Cli, info *godoc.PageInfo, dir, page string) (*template.Template, error) {
+	t, err := pres.PackageText.Clone()
 	if err != nil {
-		log.Fatalf("error parsing template: %v", err)
+		return nil, err
 	}
 
-	return pres
+	utilFuncs := NewTemplateUtils(config)
+	utilFuncs.anchors.ReservePage(info)
+	for _, group := range symbolGroups(info, *config.Order, utilFuncs.forge) {
+		utilFuncs.anchors.Reserve(group.Anchor)
+		utilFuncs.anchors.reserveHeading(group.Title)
+	}
+	utilFuncs.setSource(config, info)
+	utilFuncs.pagePath = path.Join(dir, utilFuncs.pageFilename)
+	if page != "" {
+		utilFuncs.pagePath = path.Join(dir, page)
+	}
+	if *config.Split {
+		utilFuncs.split = splitPages(info)
+		utilFuncs.page = page
+		if page == "" {
+			utilFuncs.page = utilFuncs.pageFilename
+		}
+	}
+
+	return t.Funcs(utilFuncs.Methods()), nil
+}
+
+// setSource points the source links of t at the sources of the package
+// documented by info: the module version, or the workspace module and git
+// revision, it was read from.
+func (t *TemplateUtils) setSource(config *Cli, info *godoc.PageInfo) {
+	if mv := versionOfPage(config, info); mv != nil {
+		t.module = &mv.Module
+		t.importPath = info.PDoc.ImportPath
+		t.sourceID = mv.ref()
+		return
+	}
+	if config.Workspace != nil && info.PDoc != nil {
+		t.module = config.Workspace.ModuleOf(info.PDoc.ImportPath)
+		t.importPath = info.PDoc.ImportPath
+	}
+	if config.Revision != nil {
+		t.sourceID = config.Revision.Commit
+	}
+}
+
+// parseTemplateOverrides parses the template file, or every file of the
+// template directory, at name on top of the built-in template t. Blocks
+// defined with `{{define}}` replace the built-in blocks of the same name, and
+// a file with a non-empty body replaces the built-in template entirely.
+func parseTemplateOverrides(t *template.Template, name string) error {
+	filenames, err := templateFiles(name)
+	if err != nil {
+		return err
+	}
+
+	for _, filename := range filenames {
+		buf, err := os.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		if _, err := t.Parse(string(buf)); err != nil {
+			return fmt.Errorf("%s: %v", filename, err)
+		}
+	}
+
+	return nil
+}
+
+// templateFiles returns the template file name, or the files of the template
+// directory name.
+func templateFiles(name string) ([]string, error) {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return []string{name}, nil
+	}
+
+	entries, err := os.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+	var filenames []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			filenames = append(filenames, filepath.Join(name, entry.Name()))
+		}
+	}
+	return filenames, nil
+}
+
+// replacesTemplate reports whether the template overrides at name replace the
+// built-in template entirely, rather than only some of its blocks: whether
+// one of the files has a non-empty body.
+func replacesTemplate(name string) (bool, error) {
+	filenames, err := templateFiles(name)
+	if err != nil {
+		return false, err
+	}
+
+	for _, filename := range filenames {
+		buf, err := os.ReadFile(filename)
+		if err != nil {
+			return false, err
+		}
+		// the funcs are checked when the template itself is parsed
+		tree := parse.New(filename)
+		tree.Mode = parse.SkipFuncCheck
+		if _, err := tree.Parse(string(buf), "", "", map[string]*parse.Tree{}); err != nil {
+			return false, fmt.Errorf("%s: %v", filename, err)
+		}
+		if !parse.IsEmptyTree(tree.Root) {
+			return true, nil
+		}
+	}
+	return false, nil
 }
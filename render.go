@@ -0,0 +1,328 @@
+package godoc2md
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/build"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/tools/godoc"
+	"golang.org/x/tools/godoc/vfs"
+)
+
+const (
+	target         = "/target"
+	cmdPrefix      = "cmd/"
+	toolsPath      = "golang.org/x/tools/cmd/"
+	builtinPkgPath = "builtin"
+)
+
+// CommandLine writes the documentation for the package named by args[0] to w.
+// Any remaining args are used to filter the documented symbols.
+//
+// It mirrors godoc.CommandLine, but gives godoc2md a chance to adjust the
+// extracted package documentation before the template is executed.
+func CommandLine(w io.Writer, fs vfs.NameSpace, pres *godoc.Presentation, cfg *Cli, args []string) error {
+	if _, err := siteGenerator(cfg); err != nil {
+		return err
+	}
+	if err := checkSplit(cfg); err != nil {
+		return err
+	}
+	if err := checkModel(cfg, *cfg.AltPkgTemplate); err != nil {
+		return err
+	}
+
+	if *cfg.CheckLinks == "" {
+		return renderPackage(w, fs, pres, cfg, args)
+	}
+
+	var buf bytes.Buffer
+	if err := renderPackage(&buf, fs, pres, cfg, args); err != nil {
+		return err
+	}
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+
+	checker, err := newLinkChecker(cfg)
+	if err != nil {
+		return err
+	}
+	checker.AddPage("", buf.Bytes())
+	return reportLinks(cfg, checker.Check())
+}
+
+// renderPackage writes the documentation for the package named by args[0],
+// as described by CommandLine, to w.
+func renderPackage(w io.Writer, fs vfs.NameSpace, pres *godoc.Presentation, cfg *Cli, args []string) error {
+	info, err := loadPage(fs, pres, cfg, args)
+	if err != nil {
+		return err
+	}
+	return renderPage(w, pres, cfg, info, pageDir(*cfg.BasePrefix, argImportPath(cfg, args[0])))
+}
+
+// loadPage loads the package documentation for the package named by args[0],
+// as configured by cfg.
+func loadPage(fs vfs.NameSpace, pres *godoc.Presentation, cfg *Cli, args []string) (*godoc.PageInfo, error) {
+	load := func(args []string) (*godoc.PageInfo, error) {
+		return GetPageInfo(fs, pres, args)
+	}
+
+	importPath := ""
+	if isVersioned(cfg, args[0]) {
+		mv, err := cfg.moduleVersion(args[0])
+		if err != nil {
+			return nil, err
+		}
+		load = func(args []string) (*godoc.PageInfo, error) {
+			return getVersionPageInfo(fs, pres, mv, args)
+		}
+	} else if *cfg.Rev != "" {
+		rev, err := cfg.revision()
+		if err != nil {
+			return nil, err
+		}
+		dir, pkgPath, err := revisionDir(cfg, args[0])
+		if err != nil {
+			return nil, err
+		}
+		importPath = pkgPath
+		load = func(args []string) (*godoc.PageInfo, error) {
+			if err := rev.bind(fs, dir); err != nil {
+				return nil, err
+			}
+			return getTargetPageInfo(pres, args)
+		}
+	} else if cfg.Workspace != nil {
+		if dir, pkgPath, ok := cfg.Workspace.Resolve(args[0]); ok {
+			args = append([]string{dir}, args[1:]...)
+			importPath = pkgPath
+		}
+	}
+
+	info, err := getMultiPlatformPageInfo(load, cfg, args)
+	if err != nil {
+		return nil, err
+	}
+	if importPath != "" && info.PDoc != nil {
+		info.PDoc.ImportPath = importPath
+	}
+
+	if *cfg.HideDeprecated {
+		filterDeprecated(info)
+	}
+	if err := orderSymbols(fs, info, *cfg.Order); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// renderPage writes the documentation of info in the configured format to w,
+// as the page of the directory dir, relative to the output directory.
+func renderPage(w io.Writer, pres *godoc.Presentation, cfg *Cli, info *godoc.PageInfo, dir string) error {
+	format, err := GetFormat(*cfg.Format)
+	if err != nil {
+		return err
+	}
+	if format.JSON {
+		return writeJSON(w, pres, cfg, info)
+	}
+
+	t, err := pageTemplate(pres, cfg, info, dir, "")
+	if err != nil {
+		return err
+	}
+
+	if *cfg.Model {
+		if info.PDoc == nil {
+			return fmt.Errorf("%s: no package documentation", info.Dirname)
+		}
+		return t.Execute(w, NewPackage(pres, cfg, info))
+	}
+
+	return t.Execute(w, info)
+}
+
+func writeJSON(w io.Writer, pres *godoc.Presentation, cfg *Cli, info *godoc.PageInfo) error {
+	if info.PDoc == nil {
+		return fmt.Errorf("%s: no package documentation", info.Dirname)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(NewPackage(pres, cfg, info))
+}
+
+// GetPageInfo loads the package documentation for the package named by
+// args[0], trying it as a package first and as a command second.
+//
+// Original Source https://github.com/golang/tools/blob/master/godoc/cmdline.go#L30
+func GetPageInfo(fs vfs.NameSpace, pres *godoc.Presentation, args []string) (*godoc.PageInfo, error) {
+	pkgPath := args[0]
+	cmdMode := strings.HasPrefix(pkgPath, cmdPrefix)
+
+	var abspath, relpath string
+	if cmdMode {
+		pkgPath = strings.TrimPrefix(pkgPath, cmdPrefix)
+	} else {
+		abspath, relpath = paths(fs, pres, pkgPath)
+	}
+
+	var mode godoc.PageInfoMode
+	if relpath == builtinPkgPath {
+		// the fake built-in package contains unexported identifiers
+		mode = godoc.NoFiltering | godoc.NoTypeAssoc
+	}
+	if pres.AllMode {
+		mode |= godoc.NoFiltering
+	}
+
+	// First, try as package unless forced as command.
+	var info *godoc.PageInfo
+	if !cmdMode {
+		info = pres.GetPkgPageInfo(abspath, relpath, mode)
+	}
+
+	// Second, try as command (if the path is not absolute).
+	var cinfo *godoc.PageInfo
+	if !filepath.IsAbs(pkgPath) {
+		abspath = path.Join(pres.PkgFSRoot(), toolsPath+pkgPath)
+		cinfo = pres.GetCmdPageInfo(abspath, relpath, mode)
+		if cinfo.IsEmpty() {
+			abspath = path.Join(pres.CmdFSRoot(), cmdPrefix, pkgPath)
+			cinfo = pres.GetCmdPageInfo(abspath, relpath, mode)
+		}
+	}
+
+	// determine what to use
+	if info == nil || info.IsEmpty() {
+		if cinfo != nil && !cinfo.IsEmpty() {
+			info = cinfo
+		}
+	} else if cinfo != nil && !cinfo.IsEmpty() && info.PAst == nil && info.PDoc == nil {
+		info = cinfo
+	}
+
+	if info == nil {
+		return nil, fmt.Errorf("%s: no such directory or package", args[0])
+	}
+	if info.Err != nil {
+		return nil, info.Err
+	}
+
+	return filterPageInfo(info, args)
+}
+
+// filterPageInfo names the package loaded from the virtual "/target"
+// directory after args[0], and filters its symbols by the remaining args.
+func filterPageInfo(info *godoc.PageInfo, args []string) (*godoc.PageInfo, error) {
+	if info.PDoc != nil && info.PDoc.ImportPath == target {
+		// Replace virtual /target with actual argument from command line.
+		info.PDoc.ImportPath = args[0]
+	}
+
+	// If we have more than one argument, use the remaining arguments for filtering.
+	if len(args) > 1 && info.PDoc != nil {
+		rx, err := makeRx(args[1:])
+		if err != nil {
+			return nil, fmt.Errorf("illegal regular expression from %v: %v", args[1:], err)
+		}
+		info.IsFiltered = true
+		info.PDoc.Filter(rx.MatchString)
+	}
+
+	return info, nil
+}
+
+// getTargetPageInfo loads the package documentation for the package bound on
+// the virtual "/target" directory, named by args[0].
+func getTargetPageInfo(pres *godoc.Presentation, args []string) (*godoc.PageInfo, error) {
+	var mode godoc.PageInfoMode
+	if pres.AllMode {
+		mode |= godoc.NoFiltering
+	}
+	info := pres.GetPkgPageInfo(target, target, mode)
+	if info == nil || info.IsEmpty() {
+		return nil, fmt.Errorf("%s: no such directory or package", args[0])
+	}
+	if info.Err != nil {
+		return nil, info.Err
+	}
+
+	return filterPageInfo(info, args)
+}
+
+// revisionDir returns the directory of the working tree, and the import path,
+// of the package named by arg, read at the revision of the `-rev` flag. The
+// import path is empty outside of workspaces.
+func revisionDir(cfg *Cli, arg string) (dir, importPath string, err error) {
+	if cfg.Workspace != nil {
+		if dir, importPath, ok := cfg.Workspace.Resolve(arg); ok {
+			return dir, importPath, nil
+		}
+	}
+	if !filepath.IsAbs(arg) && !build.IsLocalImport(arg) {
+		return "", "", fmt.Errorf("%s: -rev requires a directory or a package of the workspace", arg)
+	}
+	dir, err = filepath.Abs(arg)
+	return dir, "", err
+}
+
+// paths maps operating system paths like . or ./foo or /foo/bar onto the
+// virtual "/target" directory of the name space, so that they can be loaded
+// like any other package. Returns the absolute and relative paths.
+//
+// Original Source https://github.com/golang/tools/blob/master/godoc/cmdline.go#L134
+func paths(fs vfs.NameSpace, pres *godoc.Presentation, pkgPath string) (abspath, relpath string) {
+	if filepath.IsAbs(pkgPath) {
+		fs.Bind(target, vfs.OS(pkgPath), "/", vfs.BindReplace)
+		return target, target
+	}
+	if build.IsLocalImport(pkgPath) {
+		cwd, err := os.Getwd()
+		if err != nil {
+			log.Printf("error while getting working directory: %v", err)
+		}
+		pkgPath = filepath.Join(cwd, pkgPath)
+		fs.Bind(target, vfs.OS(pkgPath), "/", vfs.BindReplace)
+		return target, target
+	}
+	bp, err := build.Import(pkgPath, "", build.FindOnly)
+	if err != nil {
+		log.Printf("error while importing build package: %v", err)
+	}
+	if bp.Dir != "" && bp.ImportPath != "" {
+		fs.Bind(target, vfs.OS(bp.Dir), "/", vfs.BindReplace)
+		return target, bp.ImportPath
+	}
+	return path.Join(pres.PkgFSRoot(), pkgPath), pkgPath
+}
+
+// makeRx makes a regular expression of the form
+// names[0]|names[1]|...names[len(names)-1].
+func makeRx(names []string) (*regexp.Regexp, error) {
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no expression provided")
+	}
+	s := ""
+	for i, name := range names {
+		if i > 0 {
+			s += "|"
+		}
+		if strings.ContainsAny(name, ".(|)*+?^$[]") {
+			s += name
+		} else {
+			s += "^" + name + "$" // must match exactly
+		}
+	}
+	return regexp.Compile(s)
+}
@@ -0,0 +1,146 @@
+package godoc2md
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"text/template" // for HTMLEscape
+)
+
+// Renderer writes the blocks of a doc comment, as split up by RenderComment,
+// in a specific markup language.
+type Renderer interface {
+	// Paragraph writes a paragraph. Its text has already been passed through
+	// Escape and Link, and keeps the line breaks of the comment.
+	Paragraph(w io.Writer, text string)
+	// Heading writes a section heading, along with the anchor id computed for
+	// it.
+	Heading(w io.Writer, text, id string)
+	// CodeBlock writes preformatted lines verbatim. Lang is the language of
+	// the code, or empty if it is unknown.
+	CodeBlock(w io.Writer, lang string, lines []string)
+	// List writes a bulleted, or numbered if ordered is set, list. Items have
+	// already been passed through Escape and Link.
+	List(w io.Writer, items []string, ordered bool)
+	// Deprecation writes the callout of the `Deprecated:` paragraph of a
+	// doc comment. The notice has already been rendered, with the prefix
+	// removed.
+	Deprecation(w io.Writer, notice string)
+	// Strike returns the markup of text struck through, or otherwise marked
+	// as deprecated where the markup has no strike-through.
+	Strike(text string) string
+	// Link returns the markup of a link to url, labeled text.
+	Link(url, text string) string
+	// Escape returns text with the characters meaningful to the markup
+	// escaped.
+	Escape(text string) string
+}
+
+// preRenderer is implemented by the renderers writing the indented spans of
+// doc comments, lists included, themselves.
+type preRenderer interface {
+	// Pre writes the unindented lines of an indented span.
+	Pre(w io.Writer, lines []string)
+}
+
+var (
+	htmlA    = []byte(`<a href="`)
+	htmlAq   = []byte(`">`)
+	htmlEnda = []byte("</a>")
+
+	mdPreline = []byte("```")
+	mdNewline = []byte("\n")
+	mdH3      = []byte("### ")
+)
+
+// MarkdownRenderer renders doc comments as GitHub flavored Markdown.
+type MarkdownRenderer struct{}
+
+// Paragraph implements Renderer.
+func (MarkdownRenderer) Paragraph(w io.Writer, text string) {
+	_, _ = io.WriteString(w, text)
+	_, _ = w.Write(mdNewline) // trailing newline to emulate </p>
+}
+
+// Heading implements Renderer. The heading is given an explicit anchor, as
+// the forge's own slug of it may be taken by an earlier heading of the page.
+func (MarkdownRenderer) Heading(w io.Writer, text, id string) {
+	_, _ = w.Write(mdH3)
+	_, _ = io.WriteString(w, `<a name="`+id+`">`)
+	_, _ = io.WriteString(w, text)
+	_, _ = w.Write(htmlEnda)
+	_, _ = w.Write(mdNewline)
+}
+
+// CodeBlock implements Renderer.
+func (MarkdownRenderer) CodeBlock(w io.Writer, lang string, lines []string) {
+	_, _ = w.Write(mdPreline)
+	_, _ = io.WriteString(w, lang)
+	_, _ = w.Write(mdNewline)
+	for _, line := range lines {
+		_, _ = io.WriteString(w, line)
+	}
+	_, _ = w.Write(mdPreline)
+	_, _ = w.Write(mdNewline)
+	_, _ = w.Write(mdNewline)
+}
+
+// Pre writes an indented span of a doc comment as a code block, with its
+// URLs converted into links. List markers are left as they are, so that
+// Markdown output is unchanged by the list support of the other renderers.
+func (r MarkdownRenderer) Pre(w io.Writer, lines []string) {
+	_, _ = w.Write(mdPreline)
+	_, _ = w.Write(mdNewline)
+	for _, line := range lines {
+		emphasize(w, r, line)
+	}
+	_, _ = w.Write(mdPreline)
+	_, _ = w.Write(mdNewline)
+	_, _ = w.Write(mdNewline)
+}
+
+// List implements Renderer.
+func (MarkdownRenderer) List(w io.Writer, items []string, ordered bool) {
+	for i, item := range items {
+		marker := "* "
+		if ordered {
+			marker = strconv.Itoa(i+1) + ". "
+		}
+		_, _ = io.WriteString(w, marker+item)
+		_, _ = w.Write(mdNewline)
+	}
+	_, _ = w.Write(mdNewline)
+}
+
+// Deprecation implements Renderer, as a blockquote.
+func (MarkdownRenderer) Deprecation(w io.Writer, notice string) {
+	lines := strings.Split(strings.TrimRight(notice, "\n"), "\n")
+	lines[0] = "**Deprecated:** " + lines[0]
+	for _, line := range lines {
+		_, _ = io.WriteString(w, strings.TrimRight("> "+line, " ")+"\n")
+	}
+	_, _ = w.Write(mdNewline)
+}
+
+// Strike implements Renderer.
+func (MarkdownRenderer) Strike(text string) string {
+	return "~~" + text + "~~"
+}
+
+// Link implements Renderer.
+func (MarkdownRenderer) Link(url, text string) string {
+	var buf bytes.Buffer
+	buf.Write(htmlA)
+	template.HTMLEscape(&buf, []byte(url))
+	buf.Write(htmlAq)
+	buf.WriteString(text)
+	buf.Write(htmlEnda)
+	return buf.String()
+}
+
+// Escape implements Renderer. Comment text is passed through to Markdown
+// as-is.
+func (MarkdownRenderer) Escape(text string) string {
+	return text
+}
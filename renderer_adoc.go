@@ -0,0 +1,67 @@
+package godoc2md
+
+import (
+	"io"
+	"strings"
+)
+
+// AsciiDocRenderer renders doc comments as AsciiDoc, as consumed by
+// Asciidoctor and Antora.
+type AsciiDocRenderer struct{}
+
+// Paragraph implements Renderer.
+func (AsciiDocRenderer) Paragraph(w io.Writer, text string) {
+	_, _ = io.WriteString(w, strings.TrimRight(text, "\n")+"\n\n")
+}
+
+// Heading implements Renderer. Headings in doc comments are rendered as
+// discrete headings, so they do not break up the document's sections.
+func (AsciiDocRenderer) Heading(w io.Writer, text, id string) {
+	_, _ = io.WriteString(w, "[discrete#"+id+"]\n=== "+text+"\n\n")
+}
+
+// CodeBlock implements Renderer.
+func (AsciiDocRenderer) CodeBlock(w io.Writer, lang string, lines []string) {
+	if lang != "" {
+		_, _ = io.WriteString(w, "[source,"+lang+"]\n")
+	}
+	_, _ = io.WriteString(w, "----\n")
+	_, _ = io.WriteString(w, strings.TrimRight(strings.Join(lines, ""), "\n")+"\n")
+	_, _ = io.WriteString(w, "----\n\n")
+}
+
+// List implements Renderer.
+func (AsciiDocRenderer) List(w io.Writer, items []string, ordered bool) {
+	marker := "* "
+	if ordered {
+		marker = ". "
+	}
+	for _, item := range items {
+		_, _ = io.WriteString(w, marker+item+"\n")
+	}
+	_, _ = io.WriteString(w, "\n")
+}
+
+// Deprecation implements Renderer, as a WARNING admonition block.
+func (AsciiDocRenderer) Deprecation(w io.Writer, notice string) {
+	_, _ = io.WriteString(w, "[WARNING]\n====\n*Deprecated:* "+strings.TrimRight(notice, "\n")+"\n====\n\n")
+}
+
+// Strike implements Renderer, with the line-through role of Asciidoctor.
+func (AsciiDocRenderer) Strike(text string) string {
+	return "[.line-through]#" + text + "#"
+}
+
+// Link implements Renderer. Bare URLs are autolinked by AsciiDoc.
+func (AsciiDocRenderer) Link(url, text string) string {
+	if url == text {
+		return url
+	}
+	return url + "[" + strings.Replace(text, "]", "\\]", -1) + "]"
+}
+
+// Escape implements Renderer. Comment text is passed through to AsciiDoc
+// as-is.
+func (AsciiDocRenderer) Escape(text string) string {
+	return text
+}
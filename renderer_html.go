@@ -0,0 +1,79 @@
+package godoc2md
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"text/template" // for HTMLEscape
+)
+
+// HTMLRenderer renders doc comments as HTML fragments.
+type HTMLRenderer struct{}
+
+// Paragraph implements Renderer.
+func (HTMLRenderer) Paragraph(w io.Writer, text string) {
+	_, _ = io.WriteString(w, "<p>\n"+text+"</p>\n")
+}
+
+// Heading implements Renderer.
+func (HTMLRenderer) Heading(w io.Writer, text, id string) {
+	_, _ = io.WriteString(w, `<h3 id="`+id+`">`)
+	template.HTMLEscape(w, []byte(text))
+	_, _ = io.WriteString(w, "</h3>\n")
+}
+
+// CodeBlock implements Renderer.
+func (HTMLRenderer) CodeBlock(w io.Writer, lang string, lines []string) {
+	if lang != "" {
+		_, _ = io.WriteString(w, `<pre><code class="language-`+lang+`">`)
+	} else {
+		_, _ = io.WriteString(w, "<pre><code>")
+	}
+	template.HTMLEscape(w, []byte(strings.Join(lines, "")))
+	_, _ = io.WriteString(w, "</code></pre>\n")
+}
+
+// List implements Renderer.
+func (HTMLRenderer) List(w io.Writer, items []string, ordered bool) {
+	tag := "ul"
+	if ordered {
+		tag = "ol"
+	}
+	_, _ = io.WriteString(w, "<"+tag+">\n")
+	for _, item := range items {
+		_, _ = io.WriteString(w, "<li>"+item+"</li>\n")
+	}
+	_, _ = io.WriteString(w, "</"+tag+">\n")
+}
+
+// Deprecation implements Renderer, as a div of the deprecated-notice class.
+func (HTMLRenderer) Deprecation(w io.Writer, notice string) {
+	label := "<strong>Deprecated:</strong> "
+	if strings.HasPrefix(notice, "<p>\n") {
+		notice = "<p>\n" + label + strings.TrimPrefix(notice, "<p>\n")
+	} else {
+		notice = "<p>" + label + "</p>\n" + notice
+	}
+	_, _ = io.WriteString(w, `<div class="deprecated-notice">`+"\n"+notice+"</div>\n")
+}
+
+// Strike implements Renderer.
+func (HTMLRenderer) Strike(text string) string {
+	return "<del>" + text + "</del>"
+}
+
+// Link implements Renderer.
+func (r HTMLRenderer) Link(url, text string) string {
+	var buf bytes.Buffer
+	buf.Write(htmlA)
+	template.HTMLEscape(&buf, []byte(url))
+	buf.Write(htmlAq)
+	buf.WriteString(r.Escape(text))
+	buf.Write(htmlEnda)
+	return buf.String()
+}
+
+// Escape implements Renderer.
+func (HTMLRenderer) Escape(text string) string {
+	return template.HTMLEscapeString(text)
+}
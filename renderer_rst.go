@@ -0,0 +1,91 @@
+package godoc2md
+
+import (
+	"io"
+	"strings"
+)
+
+var rstEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"*", `\*`,
+	"`", "\\`",
+	"_", `\_`,
+	"|", `\|`,
+)
+
+// RSTRenderer renders doc comments as reStructuredText, as consumed by
+// docutils and Sphinx.
+type RSTRenderer struct{}
+
+// Paragraph implements Renderer.
+func (RSTRenderer) Paragraph(w io.Writer, text string) {
+	_, _ = io.WriteString(w, strings.TrimRight(text, "\n")+"\n\n")
+}
+
+// Heading implements Renderer. Headings in doc comments are rendered as
+// rubrics, so they do not break up the document's sections.
+func (RSTRenderer) Heading(w io.Writer, text, id string) {
+	_, _ = io.WriteString(w, ".. _"+id+":\n\n.. rubric:: "+text+"\n\n")
+}
+
+// CodeBlock implements Renderer.
+func (RSTRenderer) CodeBlock(w io.Writer, lang string, lines []string) {
+	if lang != "" {
+		_, _ = io.WriteString(w, ".. code-block:: "+lang+"\n\n")
+	} else {
+		_, _ = io.WriteString(w, "::\n\n")
+	}
+	for _, line := range strings.SplitAfter(strings.TrimRight(strings.Join(lines, ""), "\n"), "\n") {
+		if isBlank(line) {
+			_, _ = io.WriteString(w, line)
+			continue
+		}
+		_, _ = io.WriteString(w, "    "+line)
+	}
+	_, _ = io.WriteString(w, "\n\n")
+}
+
+// List implements Renderer.
+func (RSTRenderer) List(w io.Writer, items []string, ordered bool) {
+	marker := "- "
+	if ordered {
+		marker = "#. "
+	}
+	for _, item := range items {
+		_, _ = io.WriteString(w, marker+item+"\n")
+	}
+	_, _ = io.WriteString(w, "\n")
+}
+
+// Deprecation implements Renderer, as a warning directive.
+func (RSTRenderer) Deprecation(w io.Writer, notice string) {
+	_, _ = io.WriteString(w, ".. warning::\n\n")
+	for _, line := range strings.SplitAfter(strings.TrimRight("**Deprecated:** "+notice, "\n"), "\n") {
+		if isBlank(line) {
+			_, _ = io.WriteString(w, line)
+			continue
+		}
+		_, _ = io.WriteString(w, "   "+line)
+	}
+	_, _ = io.WriteString(w, "\n\n")
+}
+
+// Strike implements Renderer. reStructuredText has no strike-through, nor
+// nested markup to style the labels of links with, so text is suffixed
+// instead.
+func (RSTRenderer) Strike(text string) string {
+	return text + " (deprecated)"
+}
+
+// Link implements Renderer. Bare URLs are autolinked by reStructuredText.
+func (r RSTRenderer) Link(url, text string) string {
+	if url == text {
+		return url
+	}
+	return "`" + r.Escape(text) + " <" + url + ">`__"
+}
+
+// Escape implements Renderer.
+func (RSTRenderer) Escape(text string) string {
+	return rstEscaper.Replace(text)
+}
@@ -0,0 +1,218 @@
+package godoc2md
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/tools/godoc/vfs"
+)
+
+// Revision is a commit of the repository of the current directory, that the
+// `-rev` flag reads the packages of the repository from instead of its
+// working tree.
+type Revision struct {
+	// Rev is the revision as given, and Commit the commit it names.
+	Rev    string
+	Commit string
+	// Root is the root directory of the working tree of the repository.
+	Root string
+
+	fs *gitFS
+}
+
+// revision returns the revision of the `-rev` flag, opening it on first use.
+func (cfg *Cli) revision() (*Revision, error) {
+	if cfg.Revision != nil {
+		return cfg.Revision, nil
+	}
+
+	var root string
+	if cfg.Workspace != nil {
+		root = cfg.Workspace.Root
+	} else {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		root = findRepoRoot(cwd)
+	}
+
+	rev, err := OpenRevision(root, *cfg.Rev)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Revision = rev
+	return rev, nil
+}
+
+// OpenRevision opens the commit named by rev of the git repository whose
+// working tree is rooted at root. The object store is read directly, without
+// running git.
+func OpenRevision(root, rev string) (*Revision, error) {
+	repo, err := openGitRepo(root)
+	if err != nil {
+		return nil, err
+	}
+	commit, err := repo.resolve(rev)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := repo.commitTree(commit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Revision{
+		Rev:    rev,
+		Commit: commit.String(),
+		Root:   root,
+		fs:     &gitFS{repo: repo, tree: tree, name: rev},
+	}, nil
+}
+
+// bind mounts the directory dir of the working tree, as of the revision, on
+// the virtual "/target" directory of fs.
+func (rev *Revision) bind(fs vfs.NameSpace, dir string) error {
+	rel, err := filepath.Rel(rev.Root, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%s: not in repository %s", dir, rev.Root)
+	}
+	fs.Bind(target, rev.fs, path.Join("/", filepath.ToSlash(rel)), vfs.BindReplace)
+	return nil
+}
+
+// gitFS is the read-only file system of the tree of a commit.
+type gitFS struct {
+	repo *gitRepo
+	tree gitHash
+	name string
+}
+
+func (fs *gitFS) String() string {
+	return "git(" + fs.name + ")"
+}
+
+func (fs *gitFS) RootType(string) vfs.RootType {
+	return ""
+}
+
+// lookup returns the tree entry at the absolute path p.
+func (fs *gitFS) lookup(p string) (gitTreeEntry, error) {
+	entry := gitTreeEntry{name: "/", mode: gitModeTree, hash: fs.tree}
+	for _, elem := range strings.Split(strings.Trim(path.Clean(p), "/"), "/") {
+		if elem == "" {
+			continue
+		}
+		if !entry.isTree() {
+			return entry, &os.PathError{Op: "lookup", Path: p, Err: os.ErrNotExist}
+		}
+		entries, err := fs.repo.tree(entry.hash)
+		if err != nil {
+			return entry, err
+		}
+		found := false
+		for _, e := range entries {
+			if e.name == elem {
+				entry, found = e, true
+				break
+			}
+		}
+		if !found {
+			return entry, &os.PathError{Op: "lookup", Path: p, Err: os.ErrNotExist}
+		}
+	}
+	return entry, nil
+}
+
+func (fs *gitFS) Open(p string) (vfs.ReadSeekCloser, error) {
+	entry, err := fs.lookup(p)
+	if err != nil {
+		return nil, err
+	}
+	if entry.isTree() || entry.mode == gitModeGitlink {
+		return nil, &os.PathError{Op: "open", Path: p, Err: fmt.Errorf("is a directory")}
+	}
+	obj, err := fs.repo.object(entry.hash)
+	if err != nil {
+		return nil, err
+	}
+	return gitFile{bytes.NewReader(obj.data)}, nil
+}
+
+func (fs *gitFS) Lstat(p string) (os.FileInfo, error) {
+	return fs.Stat(p)
+}
+
+func (fs *gitFS) Stat(p string) (os.FileInfo, error) {
+	entry, err := fs.lookup(p)
+	if err != nil {
+		return nil, err
+	}
+	fi := gitFileInfo{entry: entry}
+	if !entry.isTree() && entry.mode != gitModeGitlink {
+		obj, err := fs.repo.object(entry.hash)
+		if err != nil {
+			return nil, err
+		}
+		fi.size = int64(len(obj.data))
+	}
+	return fi, nil
+}
+
+// ReadDir lists the tree at p. Submodules are left out, and the sizes of
+// files are not read.
+func (fs *gitFS) ReadDir(p string) ([]os.FileInfo, error) {
+	entry, err := fs.lookup(p)
+	if err != nil {
+		return nil, err
+	}
+	if !entry.isTree() {
+		return nil, &os.PathError{Op: "readdir", Path: p, Err: fmt.Errorf("not a directory")}
+	}
+	entries, err := fs.repo.tree(entry.hash)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []os.FileInfo
+	for _, e := range entries {
+		if e.mode != gitModeGitlink {
+			list = append(list, gitFileInfo{entry: e})
+		}
+	}
+	return list, nil
+}
+
+type gitFile struct {
+	*bytes.Reader
+}
+
+func (gitFile) Close() error {
+	return nil
+}
+
+type gitFileInfo struct {
+	entry gitTreeEntry
+	size  int64
+}
+
+func (fi gitFileInfo) Name() string       { return path.Base(fi.entry.name) }
+func (fi gitFileInfo) Size() int64        { return fi.size }
+func (fi gitFileInfo) ModTime() time.Time { return time.Time{} }
+func (fi gitFileInfo) IsDir() bool        { return fi.entry.isTree() }
+func (fi gitFileInfo) Sys() interface{}   { return nil }
+
+func (fi gitFileInfo) Mode() os.FileMode {
+	switch {
+	case fi.entry.isTree():
+		return os.ModeDir | 0o555
+	case fi.entry.mode == gitModeSymlink:
+		return os.ModeSymlink | 0o444
+	}
+	return 0o444
+}
@@ -0,0 +1,127 @@
+package godoc2md
+
+// rstTemplate is the built-in template of the `rst` format.
+var rstTemplate = `{{with .PDoc -}}
+{{- if $.IsMain -}}
+{{$title := base .ImportPath}}{{underline "=" $title}}
+{{$title}}
+{{underline "=" $title}}
+
+{{comment .Doc}}
+{{- else -}}
+{{underline "=" .Name}}
+{{.Name}}
+{{underline "=" .Name}}
+
+` + "``" + `import "{{.ImportPath}}"` + "``" + `
+
+.. contents::
+   :local:
+   :depth: 1
+
+.. _pkg-overview:
+
+Overview
+========
+
+{{doc .Doc}}.. _pkg-index:
+
+Index
+=====
+
+{{if .Consts}}- ` + "`" + `Constants <pkg-constants_>` + "`" + `_
+{{end}}{{if .Vars}}- ` + "`" + `Variables <pkg-variables_>` + "`" + `_
+{{end}}{{range .Funcs}}- ` + "`" + `{{node $ .Decl | escape | strike_deprecated .Doc}} <{{symbol_anchor "" .Name}}_>` + "`" + `_
+{{end}}{{range .Types}}{{$tname := .Name}}- ` + "`" + `{{printf "type %s" $tname | strike_deprecated .Doc}} <{{symbol_anchor "" $tname}}_>` + "`" + `_
+{{if or (is_enum .) .Funcs .Methods}}
+{{if is_enum .}}  - ` + "`" + `Values <{{symbol_anchor $tname "values"}}_>` + "`" + `_
+{{end}}{{range .Funcs}}  - ` + "`" + `{{node $ .Decl | escape | strike_deprecated .Doc}} <{{symbol_anchor "" .Name}}_>` + "`" + `_
+{{end}}{{range .Methods}}  - ` + "`" + `{{node $ .Decl | escape | strike_deprecated .Doc}} <{{symbol_anchor $tname .Name}}_>` + "`" + `_
+{{end}}
+{{end}}{{end}}{{range $marker, $item := $.Notes}}- ` + "`" + `{{noteTitle $marker}}s <pkg-note-{{$marker}}_>` + "`" + `_
+{{end}}
+{{with .Filenames}}.. _pkg-files:
+
+Package files
+-------------
+
+{{range .}}` + "`" + `{{filename .}} <{{srcfile_url .}}>` + "`" + `__ {{end}}
+
+{{end}}
+{{- with .Consts}}.. _pkg-constants:
+
+Constants
+=========
+
+{{range .}}{{node $ .Decl | go_block}}{{platforms $ .}}{{doc .Doc}}{{end}}
+{{- end}}
+{{- with .Vars}}.. _pkg-variables:
+
+Variables
+=========
+
+{{range .}}{{node $ .Decl | go_block}}{{platforms $ .}}{{doc .Doc}}{{end}}
+{{- end}}
+{{- range .Funcs}}{{$title := printf "func %s" .Name}}.. _{{symbol_anchor "" .Name}}:
+
+{{$title}}
+{{underline "=" $title}}
+
+` + "`" + `Source <{{get_full_url $ .Decl}}>` + "`" + `__
+
+{{node $ .Decl | go_block}}{{platforms $ .}}{{doc .Doc}}
+{{- end}}
+{{- range .Types}}{{$tname := .Name}}{{$title := printf "type %s" .Name}}.. _{{symbol_anchor "" $tname}}:
+
+{{$title}}
+{{underline "=" $title}}
+
+` + "`" + `Source <{{get_full_url $ .Decl}}>` + "`" + `__
+
+{{node $ .Decl | go_block}}{{platforms $ .}}{{doc .Doc}}
+{{- if is_enum .}}.. _{{symbol_anchor $tname "values"}}:
+
+Values
+------
+
+{{range .Consts}}{{node $ .Decl | go_block}}{{platforms $ .}}{{doc .Doc}}{{end}}
+{{- if has_stringer .}}` + "``" + `{{$tname}}` + "``" + ` values print by name through ` + "`" + `String <{{symbol_anchor $tname "String"}}_>` + "`" + `_.
+
+{{end}}
+{{- else}}{{range .Consts}}{{node $ .Decl | go_block}}{{platforms $ .}}{{doc .Doc}}{{end}}
+{{- end}}
+{{- range .Vars}}{{node $ .Decl | go_block}}{{platforms $ .}}{{doc .Doc}}{{end}}
+{{- range .Funcs}}{{$title := printf "func %s" .Name}}.. _{{symbol_anchor "" .Name}}:
+
+{{$title}}
+{{underline "-" $title}}
+
+` + "`" + `Source <{{get_full_url $ .Decl}}>` + "`" + `__
+
+{{node $ .Decl | go_block}}{{platforms $ .}}{{doc .Doc}}
+{{- end}}
+{{- range .Methods}}{{$title := printf "func (%s) %s" .Recv .Name}}.. _{{symbol_anchor $tname .Name}}:
+
+{{escape $title}}
+{{underline "-" (escape $title)}}
+
+` + "`" + `Source <{{get_full_url $ .Decl}}>` + "`" + `__
+
+{{node $ .Decl | go_block}}{{platforms $ .}}{{doc .Doc}}
+{{- end}}
+{{- end}}
+{{- range $marker, $content := $.Notes}}{{$title := printf "%ss" (noteTitle $marker)}}.. _pkg-note-{{$marker}}:
+
+{{$title}}
+{{underline "=" $title}}
+
+{{range .}}- {{with .UID}}**{{escape .}}** {{end}}(` + "`" + `source <{{note_url $ .}}>` + "`" + `__): {{oneline .Body | escape}}
+{{end}}
+{{end}}
+{{- end}}
+----
+
+| Created: {{current_time}}
+| Generated by ` + "`" + `godoc2md <https://github.com/chriswgerber/godoc2md>` + "`" + `__
+{{end -}}
+`
@@ -0,0 +1,293 @@
+package godoc2md
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"log"
+	"net"
+	"net/http"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+	"golang.org/x/tools/godoc"
+	"golang.org/x/tools/godoc/vfs"
+)
+
+// ServeCmd is the name of the subcommand serving a live preview of the
+// documentation, as in `godoc2md serve ./pkg/a ./pkg/b`.
+const ServeCmd = "serve"
+
+// eventsPath is the URL of the server-sent events telling the preview pages
+// to reload.
+const eventsPath = "/_events"
+
+// previewTemplate is the HTML page wrapping the preview of a Markdown page.
+var previewTemplate = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { max-width: 980px; margin: 0 auto; padding: 32px; font: 16px/1.5 -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #1f2328; }
+a { color: #0969da; text-decoration: none; }
+a:hover { text-decoration: underline; }
+h1, h2 { padding-bottom: .3em; border-bottom: 1px solid #d1d9e0; }
+code, pre { font: 85% ui-monospace, SFMono-Regular, Menlo, Consolas, monospace; background: #f6f8fa; border-radius: 6px; }
+code { padding: .2em .4em; }
+pre { padding: 16px; overflow: auto; }
+pre code { padding: 0; background: none; }
+blockquote { margin: 0; padding: 0 1em; color: #59636e; border-left: .25em solid #d1d9e0; }
+table { border-collapse: collapse; }
+td, th { padding: 6px 13px; border: 1px solid #d1d9e0; }
+.error { color: #d1242f; white-space: pre-wrap; }
+</style>
+</head>
+<body>
+{{.Body}}
+<script>
+new EventSource({{.Events}}).onmessage = function() { location.reload(); };
+</script>
+</body>
+</html>
+`))
+
+// previewServer renders the pages of packages on demand, as HTML converted
+// from their GitHub-flavored Markdown, and tells the open pages to reload
+// when the sources of their packages change.
+type previewServer struct {
+	fs   vfs.NameSpace
+	pres *godoc.Presentation
+	cfg  *Cli
+	md   goldmark.Markdown
+	// forge slugs the heading ids, as the forge does when it renders the
+	// Markdown.
+	forge Forge
+	// pages are the packages, by the URL path of their page. They are laid
+	// out like the pages of a multi-package run, so that links between them
+	// resolve.
+	pages map[string]string
+
+	// mu serializes the loading of packages, which binds them in the shared
+	// name space, and the polling of their sources.
+	mu    sync.Mutex
+	watch *sourceWatch
+
+	clientsMu sync.Mutex
+	clients   map[chan struct{}]bool
+}
+
+// ServeCommand serves a live preview of the documentation of the packages
+// named in args on the loopback address of the `-http` flag. The packages are
+// rendered when their page is requested, and the pages open in a browser are
+// reloaded when the sources of their package, or the template, change.
+func ServeCommand(fs vfs.NameSpace, pres *godoc.Presentation, cfg *Cli, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: %s %s package [more-packages ...]", cmdName, ServeCmd)
+	}
+	if *cfg.Format != "md" {
+		return fmt.Errorf("%s previews the md format only", ServeCmd)
+	}
+	if err := checkModel(cfg, *cfg.AltPkgTemplate); err != nil {
+		return err
+	}
+	addr := *cfg.HTTP
+	if err := checkLoopback(addr); err != nil {
+		return err
+	}
+
+	cfg.Packages = args
+	filename, err := pageFilename(cfg)
+	if err != nil {
+		return err
+	}
+	forge, err := GetForge(*cfg.Forge)
+	if err != nil {
+		return err
+	}
+
+	s := &previewServer{
+		fs:   fs,
+		pres: pres,
+		cfg:  cfg,
+		md: goldmark.New(
+			goldmark.WithExtensions(extension.GFM),
+			goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+			goldmark.WithRendererOptions(html.WithUnsafe()),
+		),
+		forge:   forge,
+		pages:   map[string]string{},
+		watch:   newSourceWatch(cfg, args),
+		clients: map[chan struct{}]bool{},
+	}
+	for _, arg := range args {
+		s.pages["/"+path.Join(pageDir(*cfg.BasePrefix, argImportPath(cfg, arg)), filename)] = arg
+	}
+	go s.poll()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.servePage)
+	mux.HandleFunc(eventsPath, s.serveEvents)
+
+	log.Printf("serving the documentation of %d packages on http://%s/", len(args), addr)
+	return http.ListenAndServe(addr, mux)
+}
+
+// checkLoopback makes sure the preview server is only reachable from the
+// local host.
+func checkLoopback(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return fmt.Errorf("%s: the preview server only listens on localhost", addr)
+	}
+	return nil
+}
+
+// servePage serves the index of the packages, or the page of one of them.
+func (s *previewServer) servePage(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/" {
+		s.serveIndex(w)
+		return
+	}
+	arg, ok := s.pages[r.URL.Path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	var buf bytes.Buffer
+	s.mu.Lock()
+	err := renderPackage(&buf, s.fs, s.pres, s.cfg, []string{arg})
+	s.mu.Unlock()
+	if err != nil {
+		// errors are shown in the page, which still reloads once fixed
+		w.WriteHeader(http.StatusInternalServerError)
+		s.writePreview(w, arg, template.HTML(`<pre class="error">`+template.HTMLEscapeString(err.Error())+`</pre>`))
+		return
+	}
+
+	var body bytes.Buffer
+	ids := parser.WithIDs(headingIDs{NewAnchors(s.forge)})
+	if err := s.md.Convert(buf.Bytes(), &body, parser.WithContext(parser.NewContext(ids))); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.writePreview(w, arg, template.HTML(body.String()))
+}
+
+// headingIDs gives the headings of a preview the ids the forge gives them.
+type headingIDs struct {
+	anchors *Anchors
+}
+
+func (ids headingIDs) Generate(value []byte, kind ast.NodeKind) []byte {
+	return []byte(ids.anchors.Heading(headingText(string(value))))
+}
+
+func (ids headingIDs) Put(value []byte) {
+	ids.anchors.Reserve(string(value))
+}
+
+func (s *previewServer) serveIndex(w http.ResponseWriter) {
+	urls := make([]string, 0, len(s.pages))
+	for url := range s.pages {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+
+	var buf bytes.Buffer
+	buf.WriteString("<h1>Packages</h1>\n<ul>\n")
+	for _, url := range urls {
+		fmt.Fprintf(&buf, "<li><a href=\"%s\">%s</a></li>\n", template.HTMLEscapeString(url), template.HTMLEscapeString(s.pages[url]))
+	}
+	buf.WriteString("</ul>\n")
+	s.writePreview(w, "Packages", template.HTML(buf.String()))
+}
+
+func (s *previewServer) writePreview(w http.ResponseWriter, title string, body template.HTML) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := previewTemplate.Execute(w, struct {
+		Title  string
+		Body   template.HTML
+		Events string
+	}{title, body, eventsPath})
+	if err != nil {
+		log.Print(err)
+	}
+}
+
+// serveEvents streams a server-sent event to the page whenever the sources
+// change, until the page is closed.
+func (s *previewServer) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher.Flush()
+
+	reload := make(chan struct{}, 1)
+	s.clientsMu.Lock()
+	s.clients[reload] = true
+	s.clientsMu.Unlock()
+	defer func() {
+		s.clientsMu.Lock()
+		delete(s.clients, reload)
+		s.clientsMu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-reload:
+			fmt.Fprint(w, "data: reload\n\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// poll tells the open pages to reload whenever the sources of the packages,
+// or the template, change.
+func (s *previewServer) poll() {
+	for {
+		time.Sleep(watchInterval)
+
+		s.mu.Lock()
+		changed, template := s.watch.changes()
+		if template {
+			if err := reloadTemplate(s.pres, s.cfg); err != nil {
+				log.Printf("error parsing template: %v", err)
+			}
+		}
+		s.mu.Unlock()
+		if len(changed) == 0 && !template {
+			continue
+		}
+
+		s.clientsMu.Lock()
+		for reload := range s.clients {
+			select {
+			case reload <- struct{}{}:
+			default:
+				// a reload is pending already
+			}
+		}
+		s.clientsMu.Unlock()
+	}
+}
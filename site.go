@@ -0,0 +1,267 @@
+package godoc2md
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/godoc"
+	"golang.org/x/tools/godoc/vfs"
+)
+
+// SitePage is the page generated for one of the packages of a multi-package
+// run.
+type SitePage struct {
+	// ImportPath of the documented package, resolved in the workspace for
+	// directories given on the command line.
+	ImportPath string
+	// Dir is the directory of the page, relative to the output directory.
+	Dir string
+	// Title, Synopsis and Weight are written into the front matter of the
+	// page by the `-site` presets. Weight is the position of the package on
+	// the command line, starting at 1.
+	Title    string
+	Synopsis string
+	Weight   int
+}
+
+// WriteSite renders every package named in args into its own page below the
+// configured output directory. Pages are laid out by import path, with the
+// configured basePrefix removed, and named after the output format.
+func WriteSite(fs vfs.NameSpace, pres *godoc.Presentation, cfg *Cli, args []string) error {
+	filename, err := pageFilename(cfg)
+	if err != nil {
+		return err
+	}
+	gen, err := siteGenerator(cfg)
+	if err != nil {
+		return err
+	}
+	if err := checkSplit(cfg); err != nil {
+		return err
+	}
+	if err := checkModel(cfg, *cfg.AltPkgTemplate); err != nil {
+		return err
+	}
+
+	var checker *LinkChecker
+	if *cfg.CheckLinks != "" {
+		if checker, err = newLinkChecker(cfg); err != nil {
+			return err
+		}
+	}
+
+	if err := openSources(cfg, args); err != nil {
+		return err
+	}
+	keys, err := cacheKeys(cfg, args)
+	if err != nil {
+		return err
+	}
+
+	var pages []SitePage
+	for _, r := range writeSitePages(fs, pres, cfg, gen, filename, args, keys) {
+		if r.err != nil {
+			return r.err
+		}
+		pages = append(pages, r.site)
+		if checker != nil {
+			for name, buf := range r.content {
+				checker.AddPage(path.Join(path.Dir(r.page), name), buf)
+			}
+		}
+	}
+
+	if gen != nil {
+		if err := gen.writeNav(*cfg.OutDir, pages); err != nil {
+			return err
+		}
+	}
+	if checker != nil {
+		return reportLinks(cfg, checker.Check())
+	}
+	return nil
+}
+
+// sitePageResult is the outcome of writing the page of a package, as returned
+// by writeSitePage.
+type sitePageResult struct {
+	page    string
+	content map[string][]byte
+	site    SitePage
+	err     error
+}
+
+// writeSitePages writes the pages of the packages named in args with
+// `-parallel` workers, reusing the pages of the `-cache` directory for the
+// packages that haven't changed, by their keys. The results are in the order
+// of args.
+func writeSitePages(fs vfs.NameSpace, pres *godoc.Presentation, cfg *Cli, gen *SiteGenerator, filename string, args, keys []string) []sitePageResult {
+	workers := *cfg.Parallel
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	results := make([]sitePageResult, len(args))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for n := 0; n < workers && n < len(args); n++ {
+		wfs, wpres := fs, pres
+		if workers > 1 {
+			wfs, wpres = workerPresentation(fs, pres, cfg)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				r := &results[i]
+				r.page, r.content, r.site, r.err = writeCachedSitePage(wfs, wpres, cfg, gen, filename, args[i], keys[i], i+1)
+			}
+		}()
+	}
+	for i := range args {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// openSources opens the module versions, and the git revision, the packages
+// named in args are read from ahead of a parallel run, as the workers only
+// read the configuration.
+func openSources(cfg *Cli, args []string) error {
+	if *cfg.Rev != "" {
+		if _, err := cfg.revision(); err != nil {
+			return err
+		}
+	}
+	for _, arg := range args {
+		if isVersioned(cfg, arg) {
+			if _, err := cfg.moduleVersion(arg); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeSitePage writes the page documenting the package arg below the output
+// directory, as the weight-th page of the site, and returns its path relative
+// to the output directory along with the results of writePage.
+func writeSitePage(fs vfs.NameSpace, pres *godoc.Presentation, cfg *Cli, gen *SiteGenerator, filename, arg string, weight int) (string, map[string][]byte, SitePage, error) {
+	page := path.Join(pageDir(*cfg.BasePrefix, argImportPath(cfg, arg)), filename)
+	dest := filepath.Join(*cfg.OutDir, filepath.FromSlash(page))
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return "", nil, SitePage{}, err
+	}
+
+	content, site, err := writePage(dest, fs, pres, cfg, gen, arg, weight)
+	return page, content, site, err
+}
+
+// writePage writes the page documenting the package arg to filename, with the
+// front matter of the site generator gen, if any, and the pages of the split
+// layout next to it. It returns the content of the pages, by filename, and
+// the description of the package in the site.
+func writePage(filename string, fs vfs.NameSpace, pres *godoc.Presentation, cfg *Cli, gen *SiteGenerator, arg string, weight int) (map[string][]byte, SitePage, error) {
+	info, err := loadPage(fs, pres, cfg, []string{arg})
+	if err != nil {
+		return nil, SitePage{}, err
+	}
+	importPath := argImportPath(cfg, arg)
+	page := newSitePage(cfg, info, importPath, weight)
+
+	var buf bytes.Buffer
+	if gen != nil {
+		gen.writeFrontMatter(&buf, page)
+	}
+	dir := pageDir(*cfg.BasePrefix, importPath)
+	if err := renderPage(&buf, pres, cfg, info, dir); err != nil {
+		return nil, SitePage{}, err
+	}
+	content := map[string][]byte{filepath.Base(filename): buf.Bytes()}
+
+	if *cfg.Split {
+		split, err := renderSplitPages(pres, cfg, info, dir)
+		if err != nil {
+			return nil, SitePage{}, err
+		}
+		for i, sp := range split {
+			var buf bytes.Buffer
+			if gen != nil {
+				gen.writeFrontMatter(&buf, SitePage{Title: sp.title, Weight: i + 1})
+			}
+			buf.Write(sp.content)
+			content[sp.name] = buf.Bytes()
+		}
+	}
+
+	if err := writePageFiles(filepath.Dir(filename), content); err != nil {
+		return nil, SitePage{}, err
+	}
+	return content, page, nil
+}
+
+// writePageFiles writes the pages of content, by filename, into dir.
+func writePageFiles(dir string, content map[string][]byte) error {
+	for name, buf := range content {
+		if err := os.WriteFile(filepath.Join(dir, name), buf, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// argImportPath returns the import path of the package named by the argument
+// arg, resolved in the workspace as loadPage does, or arg itself for
+// versioned packages and outside of the workspace.
+func argImportPath(cfg *Cli, arg string) string {
+	if cfg.Workspace != nil && !isVersioned(cfg, arg) {
+		if _, importPath, ok := cfg.Workspace.Resolve(arg); ok {
+			return importPath
+		}
+	}
+	return arg
+}
+
+// pageDir returns the directory, relative to the output directory, of the
+// page documenting the package at importPath.
+func pageDir(basePrefix, importPath string) string {
+	dir := strings.TrimPrefix(importPath, basePrefix)
+	dir = path.Clean("/" + filepath.ToSlash(dir))
+
+	return strings.TrimPrefix(dir, "/")
+}
+
+// SitePages returns the pages generated in a multi-package run, in the order
+// the packages were given on the command line.
+func (t TemplateUtils) SitePages() []SitePage {
+	pages := make([]SitePage, 0, len(t.packages))
+	for _, pkg := range t.packages {
+		pages = append(pages, SitePage{
+			ImportPath: pkg,
+			Dir:        pageDir(t.basePrefix, pkg),
+		})
+	}
+	return pages
+}
+
+// PageLink returns the relative link from the page documenting the package at
+// from to the page documenting the package at to.
+func (t TemplateUtils) PageLink(from, to string) string {
+	rel, err := filepath.Rel(
+		filepath.FromSlash("/"+pageDir(t.basePrefix, from)),
+		filepath.FromSlash("/"+pageDir(t.basePrefix, to)),
+	)
+	if err != nil {
+		return to
+	}
+
+	return path.Join(filepath.ToSlash(rel), t.pageFilename)
+}
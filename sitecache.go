@@ -0,0 +1,281 @@
+package godoc2md
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/build"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/godoc"
+	"golang.org/x/tools/godoc/vfs"
+)
+
+// cacheEntry is the content of a file of the `-cache` directory: the pages
+// written for a package, by filename, and its description in the site.
+type cacheEntry struct {
+	Site  SitePage
+	Files map[string][]byte
+}
+
+// uncachedFlags are the flags that never change the pages.
+var uncachedFlags = map[string]bool{
+	"cache":    true,
+	"parallel": true,
+	"v":        true,
+	"watch":    true,
+}
+
+// cacheKeys returns the keys of the pages of the packages named in args in the
+// `-cache` directory, which are all empty if it isn't set. They are computed
+// ahead of a parallel run, as the workers loading packages for the platforms
+// of `-platforms` change the default build context the keys depend on.
+func cacheKeys(cfg *Cli, args []string) ([]string, error) {
+	keys := make([]string, len(args))
+	if *cfg.CacheDir == "" {
+		return keys, nil
+	}
+	for i, arg := range args {
+		key, err := cacheKey(cfg, arg)
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = key
+	}
+	return keys, nil
+}
+
+// writeCachedSitePage writes the page documenting the package arg like
+// writeSitePage, unless the `-cache` directory holds it under key already, in
+// which case the cached pages are written instead of rendering them again.
+// Packages without a key are never cached.
+func writeCachedSitePage(fs vfs.NameSpace, pres *godoc.Presentation, cfg *Cli, gen *SiteGenerator, filename, arg, key string, weight int) (string, map[string][]byte, SitePage, error) {
+	if key == "" {
+		return writeSitePage(fs, pres, cfg, gen, filename, arg, weight)
+	}
+	cacheFile := filepath.Join(*cfg.CacheDir, key+".json")
+
+	if entry, ok := readCacheEntry(cacheFile); ok {
+		page := path.Join(pageDir(*cfg.BasePrefix, argImportPath(cfg, arg)), filename)
+		dir := filepath.Join(*cfg.OutDir, filepath.FromSlash(path.Dir(page)))
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return "", nil, SitePage{}, err
+		}
+		if err := writePageFiles(dir, entry.Files); err != nil {
+			return "", nil, SitePage{}, err
+		}
+		return page, entry.Files, entry.Site, nil
+	}
+
+	page, content, site, err := writeSitePage(fs, pres, cfg, gen, filename, arg, weight)
+	if err != nil {
+		return "", nil, SitePage{}, err
+	}
+	if err := writeCacheEntry(cacheFile, cacheEntry{Site: site, Files: content}); err != nil {
+		return "", nil, SitePage{}, err
+	}
+	return page, content, site, nil
+}
+
+func readCacheEntry(filename string) (cacheEntry, bool) {
+	var entry cacheEntry
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return entry, false
+	}
+	return entry, json.Unmarshal(data, &entry) == nil
+}
+
+// writeCacheEntry writes entry to filename through a temporary file, so that
+// concurrent runs never read a partial entry.
+func writeCacheEntry(filename string, entry cacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(filename), ".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+// cacheKey returns the key of the pages of the package arg in the `-cache`
+// directory: a hash of the sources of the package and of the subdirectories
+// it lists, of the godoc2md executable and template overrides, of the flags
+// and of the packages of the run. It is empty for packages whose sources
+// aren't known, which are never cached.
+func cacheKey(cfg *Cli, arg string) (string, error) {
+	h := sha256.New()
+
+	id, err := executableID()
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(h, "godoc2md %s\n", id)
+
+	flag.VisitAll(func(f *flag.Flag) {
+		if !uncachedFlags[f.Name] {
+			fmt.Fprintf(h, "-%s=%s\n", f.Name, f.Value)
+		}
+	})
+	fmt.Fprintf(h, "basePrefix %s\n", *cfg.BasePrefix)
+	fmt.Fprintf(h, "build %s/%s %v cgo=%v\n", build.Default.GOOS, build.Default.GOARCH, build.Default.BuildTags, build.Default.CgoEnabled)
+	fmt.Fprintf(h, "packages %q\n", cfg.Packages)
+	if cfg.Workspace != nil {
+		for _, m := range cfg.Workspace.Modules {
+			fmt.Fprintf(h, "module %s %s\n", m.Path, m.RepoDir)
+		}
+	}
+	if overrides := *cfg.AltPkgTemplate; overrides != "" {
+		if err := hashFiles(h, overrides, true); err != nil {
+			return "", err
+		}
+	}
+
+	fmt.Fprintf(h, "package %s\n", arg)
+	switch {
+	case isVersioned(cfg, arg):
+		importPath, _ := splitVersion(arg)
+		mv := cfg.versionOf(importPath)
+		if mv == nil {
+			return "", nil
+		}
+		fmt.Fprintf(h, "version %s@%s\n", mv.Path, mv.Version)
+	case cfg.Revision != nil:
+		fmt.Fprintf(h, "revision %s\n", cfg.Revision.Commit)
+	default:
+		dir := sourceDir(cfg, arg)
+		if dir == "" {
+			return "", nil
+		}
+		if err := hashFiles(h, dir, false); err != nil {
+			return "", err
+		}
+		if err := hashSubdirs(h, dir); err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashFiles writes the names, relative to root, and the contents of the files
+// of the directory root to h, or of all the files below it if recursive. A
+// single file is hashed on its own.
+func hashFiles(h hash.Hash, root string, recursive bool) error {
+	var filenames []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != root && !recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			filenames = append(filenames, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Strings(filenames)
+
+	for _, filename := range filenames {
+		f, err := os.Open(filename)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, filename)
+		fmt.Fprintf(h, "file %s\n", filepath.ToSlash(rel))
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hashSubdirs writes the names of the directories below root to h, along with
+// the contents of their .go files, whose package clauses and doc comments are
+// the synopses of the Subdirectories listing of the page. The directories
+// godoc skips are left out.
+func hashSubdirs(h hash.Hash, root string) error {
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, p)
+		if d.IsDir() {
+			if p == root {
+				return nil
+			}
+			if name := d.Name(); name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+			fmt.Fprintf(h, "dir %s\n", filepath.ToSlash(rel))
+			return nil
+		}
+		if filepath.Dir(p) == root || !d.Type().IsRegular() || !strings.HasSuffix(p, ".go") {
+			return nil
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		fmt.Fprintf(h, "file %s\n", filepath.ToSlash(rel))
+		_, err = io.Copy(h, f)
+		return err
+	})
+}
+
+var (
+	executableOnce sync.Once
+	executableHash string
+	executableErr  error
+)
+
+// executableID returns a hash of the running godoc2md executable, whose
+// built-in templates and rendering the cached pages depend on.
+func executableID() (string, error) {
+	executableOnce.Do(func() {
+		var filename string
+		if filename, executableErr = os.Executable(); executableErr != nil {
+			return
+		}
+		h := sha256.New()
+		if executableErr = hashFiles(h, filename, false); executableErr == nil {
+			executableHash = hex.EncodeToString(h.Sum(nil))
+		}
+	})
+	return executableHash, executableErr
+}
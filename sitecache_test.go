@@ -0,0 +1,116 @@
+package godoc2md
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/godoc"
+	"golang.org/x/tools/godoc/vfs"
+)
+
+// cachedPage is the content the test writes into the cache entries, to tell
+// the pages copied from the cache from the rendered ones.
+const cachedPage = "cached\n"
+
+func TestWriteSiteCache(t *testing.T) {
+	root := t.TempDir()
+	writeFile := func(name, content string) {
+		t.Helper()
+		filename := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("go.mod", "module example.com/site\n\ngo 1.20\n")
+	writeFile("a/a.go", "// Package a is the first package.\npackage a\n\n// A is a.\nfunc A() {}\n")
+	writeFile("b/b.go", "// Package b is the second package.\npackage b\n\n// B is b.\nfunc B() {}\n")
+
+	ws, err := LoadModule(filepath.Join(root, "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := []string{"example.com/site/a", "example.com/site/b"}
+	cfg := *Config
+	basePrefix, outDir, cacheDir, parallel := "example.com/site", filepath.Join(root, "out"), filepath.Join(root, "cache"), 4
+	cfg.BasePrefix, cfg.OutDir, cfg.CacheDir, cfg.Parallel = &basePrefix, &outDir, &cacheDir, &parallel
+	cfg.Workspace = ws
+	cfg.Packages = args
+
+	fs := vfs.NameSpace{}
+	pres := NewPresentation(godoc.NewCorpus(fs), &cfg)
+
+	// run writes the site and returns its pages, by package.
+	run := func() map[string]string {
+		t.Helper()
+		if err := WriteSite(fs, pres, &cfg, args); err != nil {
+			t.Fatal(err)
+		}
+		pages := map[string]string{}
+		for _, pkg := range []string{"a", "b"} {
+			data, err := os.ReadFile(filepath.Join(outDir, pkg, "README.md"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			pages[pkg] = string(data)
+		}
+		return pages
+	}
+	// poison replaces the pages of the cache entries with cachedPage.
+	poison := func() {
+		t.Helper()
+		entries, err := filepath.Glob(filepath.Join(cacheDir, "*.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, filename := range entries {
+			entry, ok := readCacheEntry(filename)
+			if !ok {
+				t.Fatalf("%s: unreadable cache entry", filename)
+			}
+			entry.Files["README.md"] = []byte(cachedPage)
+			if err := writeCacheEntry(filename, entry); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	pages := run()
+	if !strings.Contains(pages["a"], "A is a.") || !strings.Contains(pages["b"], "B is b.") {
+		t.Fatalf("first run: pages = %q", pages)
+	}
+	entries, _ := filepath.Glob(filepath.Join(cacheDir, "*.json"))
+	if len(entries) != 2 {
+		t.Fatalf("first run: %d cache entries, want 2", len(entries))
+	}
+
+	poison()
+	if pages := run(); pages["a"] != cachedPage || pages["b"] != cachedPage {
+		t.Errorf("unchanged run: pages = %q, want the cached pages", pages)
+	}
+
+	writeFile("a/a.go", "// Package a is the first package.\npackage a\n\n// A is changed.\nfunc A() {}\n")
+	pages = run()
+	if !strings.Contains(pages["a"], "A is changed.") {
+		t.Errorf("changed source: page a = %q, want it rendered again", pages["a"])
+	}
+	if pages["b"] != cachedPage {
+		t.Errorf("changed source: page b = %q, want the cached page", pages["b"])
+	}
+
+	poison()
+	tabWidth := flag.Lookup("tabwidth").Value.String()
+	if err := flag.Set("tabwidth", "8"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = flag.Set("tabwidth", tabWidth) })
+	pages = run()
+	if !strings.Contains(pages["a"], "A is changed.") || !strings.Contains(pages["b"], "B is b.") {
+		t.Errorf("changed flag: pages = %q, want them rendered again", pages)
+	}
+}
@@ -0,0 +1,248 @@
+package godoc2md
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/doc"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/godoc"
+)
+
+// SiteGenerator describes one of the static site generator presets. A preset
+// prefixes every page with YAML front matter and, after a multi-package run,
+// writes the navigation files the generator reads.
+type SiteGenerator struct {
+	// Name is the value accepted by the `-site` flag.
+	Name string
+	// Filename replaces the page filename of the output format, so that
+	// the generator treats the page as the index of its directory.
+	Filename string
+	// WeightKey and SlugKey are the front matter keys of the page order
+	// and of its URL, omitted if empty.
+	WeightKey string
+	SlugKey   string
+	// slug returns the value of SlugKey for the page in dir.
+	slug func(dir string) string
+	// nav writes the navigation files for the pages below outDir.
+	nav func(g SiteGenerator, outDir string, pages []SitePage) error
+}
+
+var siteGenerators = map[string]SiteGenerator{
+	"hugo": {
+		Name:      "hugo",
+		Filename:  "_index.md",
+		WeightKey: "weight",
+		SlugKey:   "slug",
+		slug:      path.Base,
+		nav:       writeHugoSections,
+	},
+	"jekyll": {
+		Name:      "jekyll",
+		Filename:  "index.md",
+		WeightKey: "nav_order",
+		SlugKey:   "permalink",
+		slug:      func(dir string) string { return path.Clean("/"+dir) + "/" },
+	},
+	"mkdocs": {
+		Name:     "mkdocs",
+		Filename: "index.md",
+		nav:      writeMkDocsNav,
+	},
+	"docusaurus": {
+		Name:      "docusaurus",
+		Filename:  "index.md",
+		WeightKey: "sidebar_position",
+		SlugKey:   "slug",
+		slug:      func(dir string) string { return path.Clean("/" + dir) },
+		nav:       writeDocusaurusSidebars,
+	},
+}
+
+// GetSiteGenerator returns the static site generator preset registered under
+// name.
+func GetSiteGenerator(name string) (SiteGenerator, error) {
+	gen, ok := siteGenerators[name]
+	if !ok {
+		names := make([]string, 0, len(siteGenerators))
+		for name := range siteGenerators {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return SiteGenerator{}, fmt.Errorf("unknown site generator %q, must be one of: %s", name, strings.Join(names, ", "))
+	}
+	return gen, nil
+}
+
+// siteGenerator returns the preset selected by the `-site` flag, if any.
+// Presets write Markdown pages below the output directory only.
+func siteGenerator(cfg *Cli) (*SiteGenerator, error) {
+	if *cfg.Site == "" {
+		return nil, nil
+	}
+	gen, err := GetSiteGenerator(*cfg.Site)
+	if err != nil {
+		return nil, err
+	}
+	if *cfg.Format != "md" || *cfg.OutDir == "" {
+		return nil, fmt.Errorf("site generator %s requires -format md and -out", gen.Name)
+	}
+	return &gen, nil
+}
+
+// pageFilename returns the name of the page written for each package by a
+// multi-package run.
+func pageFilename(cfg *Cli) (string, error) {
+	if gen, err := siteGenerator(cfg); err != nil || gen != nil {
+		if err != nil {
+			return "", err
+		}
+		return gen.Filename, nil
+	}
+	format, err := GetFormat(*cfg.Format)
+	if err != nil {
+		return "", err
+	}
+	return format.Filename, nil
+}
+
+// newSitePage returns the page of the package documented by info, as the
+// weight-th page of the run.
+func newSitePage(cfg *Cli, info *godoc.PageInfo, importPath string, weight int) SitePage {
+	page := SitePage{
+		ImportPath: importPath,
+		Dir:        pageDir(*cfg.BasePrefix, importPath),
+		Title:      path.Base(importPath),
+		Weight:     weight,
+	}
+	if info.PDoc != nil {
+		if info.PDoc.Name != "main" {
+			page.Title = info.PDoc.Name
+		}
+		page.Synopsis = doc.Synopsis(info.PDoc.Doc)
+	}
+	return page
+}
+
+// writeFrontMatter writes the YAML front matter of page to w.
+func (g SiteGenerator) writeFrontMatter(w io.Writer, page SitePage) {
+	fmt.Fprintln(w, "---")
+	fmt.Fprintf(w, "title: %s\n", strconv.Quote(page.Title))
+	if g.WeightKey != "" {
+		fmt.Fprintf(w, "%s: %d\n", g.WeightKey, page.Weight)
+	}
+	if g.SlugKey != "" && page.Dir != "" {
+		fmt.Fprintf(w, "%s: %s\n", g.SlugKey, strconv.Quote(g.slug(page.Dir)))
+	}
+	if page.Synopsis != "" {
+		fmt.Fprintf(w, "description: %s\n", strconv.Quote(page.Synopsis))
+	}
+	fmt.Fprint(w, "---\n\n")
+}
+
+// writeNav writes the navigation files of the generator, if it has any.
+func (g SiteGenerator) writeNav(outDir string, pages []SitePage) error {
+	if g.nav == nil {
+		return nil
+	}
+	return g.nav(g, outDir, pages)
+}
+
+// writeHugoSections writes an _index.md for every directory above a package
+// page that isn't a package itself, so that Hugo renders the tree of
+// packages as nested sections.
+func writeHugoSections(g SiteGenerator, outDir string, pages []SitePage) error {
+	isPage := map[string]bool{}
+	for _, page := range pages {
+		isPage[page.Dir] = true
+	}
+
+	for _, page := range pages {
+		for dir := page.Dir; dir != ""; {
+			if dir = path.Dir(dir); dir == "." {
+				dir = ""
+			}
+			if isPage[dir] {
+				continue
+			}
+			isPage[dir] = true
+
+			title := path.Base(dir)
+			if dir == "" {
+				title = "API reference"
+			}
+			var buf strings.Builder
+			g.writeFrontMatter(&buf, SitePage{Dir: dir, Title: title})
+			if err := writeNavFile(outDir, path.Join(dir, g.Filename), buf.String()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeMkDocsNav writes mkdocs-nav.yml, the `nav` section to paste into, or
+// include from, mkdocs.yml.
+func writeMkDocsNav(g SiteGenerator, outDir string, pages []SitePage) error {
+	var buf strings.Builder
+	fmt.Fprintln(&buf, "nav:")
+	for _, page := range pages {
+		fmt.Fprintf(&buf, "  - %s: %s\n", strconv.Quote(page.ImportPath), path.Join(page.Dir, g.Filename))
+	}
+	return writeNavFile(outDir, "mkdocs-nav.yml", buf.String())
+}
+
+// docusaurusCategory is the content of a Docusaurus _category_.json file.
+type docusaurusCategory struct {
+	Label    string `json:"label"`
+	Position int    `json:"position"`
+	Link     struct {
+		Type string `json:"type"`
+		ID   string `json:"id"`
+	} `json:"link"`
+}
+
+// writeDocusaurusSidebars writes a _category_.json into every package
+// directory, linking the category to the package page, and a sidebars.js
+// listing the pages in order.
+func writeDocusaurusSidebars(g SiteGenerator, outDir string, pages []SitePage) error {
+	var ids []string
+	for _, page := range pages {
+		id := strings.TrimSuffix(path.Join(page.Dir, g.Filename), ".md")
+		ids = append(ids, id)
+		if page.Dir == "" {
+			continue
+		}
+
+		cat := docusaurusCategory{Label: page.ImportPath, Position: page.Weight}
+		cat.Link.Type = "doc"
+		cat.Link.ID = id
+		buf, err := json.MarshalIndent(cat, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := writeNavFile(outDir, path.Join(page.Dir, "_category_.json"), string(buf)+"\n"); err != nil {
+			return err
+		}
+	}
+
+	buf, err := json.MarshalIndent(map[string][]string{"api": ids}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeNavFile(outDir, "sidebars.js", "module.exports = "+string(buf)+";\n")
+}
+
+func writeNavFile(outDir, name, content string) error {
+	filename := filepath.Join(outDir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, []byte(content), 0o644)
+}
@@ -0,0 +1,143 @@
+package godoc2md
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"golang.org/x/tools/godoc"
+)
+
+// splitFuncsPage is the page documenting the package level functions in the
+// split layout.
+const splitFuncsPage = "funcs.md"
+
+// splitTypePage returns the page documenting the type typeName, with its
+// constructors and methods, in the split layout.
+func splitTypePage(typeName string) string {
+	return "type-" + typeName + ".md"
+}
+
+// checkSplit validates the `-split` flag: the split layout writes Markdown
+// pages below the output directory, executing the built-in blocks with
+// godoc.PageInfo.
+func checkSplit(cfg *Cli) error {
+	if !*cfg.Split {
+		return nil
+	}
+	if *cfg.Format != "md" || *cfg.OutDir == "" || *cfg.Model {
+		return fmt.Errorf("split layout requires -format md and -out, and can't be used with -model")
+	}
+	return nil
+}
+
+// splitPages maps the anchor ids of the symbols, and examples, of the package
+// documented by info to the page of the split layout they are rendered on.
+// Anchors of the package page are missing from the map.
+func splitPages(info *godoc.PageInfo) map[string]string {
+	pages := map[string]string{}
+	if info.PDoc == nil {
+		return pages
+	}
+
+	for _, f := range info.PDoc.Funcs {
+		pages[SymbolAnchor("", f.Name)] = splitFuncsPage
+	}
+	for _, t := range info.PDoc.Types {
+		page := splitTypePage(t.Name)
+		pages[SymbolAnchor("", t.Name)] = page
+		pages[SymbolAnchor(t.Name, "values")] = page
+		for _, f := range t.Funcs {
+			pages[SymbolAnchor("", f.Name)] = page
+		}
+		for _, m := range t.Methods {
+			pages[SymbolAnchor(t.Name, m.Name)] = page
+		}
+	}
+
+	// examples are rendered with the symbol they are named after, as in
+	// "Type_Method_suffix"
+	for _, eg := range info.Examples {
+		if name := strings.SplitN(eg.Name, "_", 2)[0]; name != "" {
+			if page, ok := pages[name]; ok {
+				pages["example_"+eg.Name] = page
+			}
+		}
+	}
+
+	return pages
+}
+
+// AnchorLink returns the link to the anchor id from the page being rendered.
+// In the split layout, anchors rendered on another page of the package are
+// prefixed with its filename.
+func (t TemplateUtils) AnchorLink(id string) string {
+	if t.split == nil {
+		return "#" + id
+	}
+
+	page := t.split[id]
+	if page == "" {
+		page = t.pageFilename
+	}
+	if page == t.page {
+		return "#" + id
+	}
+	return page + "#" + id
+}
+
+// SymbolLink returns the link to the anchor of a symbol, as named by
+// SymbolAnchor, from the page being rendered.
+func (t TemplateUtils) SymbolLink(typeName, name string) string {
+	return t.AnchorLink(SymbolAnchor(typeName, name))
+}
+
+// IsSplit reports whether the package is documented with the split layout,
+// leaving its functions and types out of the package page.
+func (t TemplateUtils) IsSplit() bool {
+	return t.split != nil
+}
+
+// splitPage is a page of the split layout, other than the package page.
+type splitPage struct {
+	name    string
+	title   string
+	content []byte
+}
+
+// renderSplitPages renders the pages of the split layout of the package
+// documented by info into the directory dir, relative to the output
+// directory: one for its package level functions, if any, and one per type.
+func renderSplitPages(pres *godoc.Presentation, cfg *Cli, info *godoc.PageInfo, dir string) ([]splitPage, error) {
+	if info.PDoc == nil {
+		return nil, nil
+	}
+
+	var pages []splitPage
+	if len(info.PDoc.Funcs) > 0 {
+		t, err := pageTemplate(pres, cfg, info, dir, splitFuncsPage)
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := t.ExecuteTemplate(&buf, "funcs_page", info); err != nil {
+			return nil, err
+		}
+		pages = append(pages, splitPage{name: splitFuncsPage, title: info.PDoc.Name + " functions", content: buf.Bytes()})
+	}
+
+	for _, typ := range info.PDoc.Types {
+		name := splitTypePage(typ.Name)
+		t, err := pageTemplate(pres, cfg, info, dir, name)
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := t.ExecuteTemplate(&buf, "type_page", Symbol{Page: info, Type: typ, Heading: "#"}); err != nil {
+			return nil, err
+		}
+		pages = append(pages, splitPage{name: name, title: "type " + typ.Name, content: buf.Bytes()})
+	}
+
+	return pages, nil
+}
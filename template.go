@@ -1,114 +1,156 @@
 package godoc2md
 
 // pkgTemplate is the default template used by the godoc2md template parser.
+//
+// It is split into named blocks, so that a custom template passed with
+// `-template` can override only some of them with `{{define "name"}}` and
+// inherit the rest:
+//
+//	header      title, import path and table of contents
+//	overview    package documentation
+//	index       symbol index, examples and package files
+//	consts      package level constants
+//	vars        package level variables
+//	funcs       package level functions
+//	func        a single function, executed with a Symbol
+//	types       package level types
+//	groups      package level functions and types, under a heading per
+//	            group, with `-order file` or `-order section`
+//	subdirs     subdirectories of the package directory
+//	type        a single type, executed with a Symbol
+//	method      a single method, executed with a Symbol
+//	notes       BUG, TODO, etc. notes
+//	funcs_page  page of the package level functions, with `-split`
+//	type_page   page of a single type, executed with a Symbol, with `-split`
+//	command     documentation of a main package, in place of the blocks above
+//	footer      generation timestamp
+//
+// Unless noted otherwise, blocks are executed with the godoc.PageInfo of the
+// package. They don't apply to `-model`, which requires a template replacing
+// the built-in one entirely, as it is executed with the Package model. The
+// templates of the other formats aren't split, and can only be replaced.
 var pkgTemplate = `{{with .PDoc -}}
-{{- if $.IsMain}}
+{{- if $.IsMain}}{{template "command" $}}{{else -}}
+{{template "header" $}}{{template "overview" $}}{{template "index" $}}
+{{- template "consts" $}}{{template "vars" $}}{{if split}}{{else if symbol_groups $}}{{template "groups" $}}{{else}}{{template "funcs" $}}{{template "types" $}}{{end}}{{template "subdirs" $}}
+{{- end}}{{template "notes" $}}{{end}}{{template "footer" $}}
+
+{{- define "command"}}{{with .PDoc}}
 > {{ base .ImportPath }}
 {{comment_md .Doc}}
-{{else -}}
-# {{ .Name }}
+{{end}}{{end}}
+
+{{- define "header"}}{{with .PDoc}}# {{ .Name }}
 
 ` + "`" + `import "{{.ImportPath}}"` + "`" + `
 
 * [Overview](#pkg-overview)
-* [Index](#pkg-index){{if $.Examples}}
+* [Index](#pkg-index){{if and show_examples $.Examples}}
 * [Examples](#pkg-examples){{- end}}{{if $.Dirs}}
 * [Subdirectories](#pkg-subdirectories){{- end}}
 
-## <a name="pkg-overview">Overview</a>
+{{end}}{{end}}
+
+{{- define "overview"}}{{with .PDoc}}## <a name="pkg-overview">Overview</a>
 
-{{comment_md .Doc -}}
-{{example_html $ "" -}}
+{{doc_md .Doc}}{{example_md $ ""}}{{end}}{{end}}
 
-## <a name="pkg-index">Index</a>
+{{- define "index"}}{{with .PDoc}}## <a name="pkg-index">Index</a>
 
 {{if .Consts -}}
 * [Constants](#pkg-constants){{end}}{{if .Vars}}
-* [Variables](#pkg-variables){{end}}{{range .Funcs -}}{{$name_html := html .Name}}
-* [{{node_html $ .Decl false | sanitize}}](#{{$name_html}}){{- end}}{{- range .Types}}{{$tname_html := html .Name}}
-* [type {{$tname_html}}](#{{$tname_html}}){{- range .Funcs}}{{$name_html := html .Name}}
-  * [{{node_html $ .Decl false | sanitize}}](#{{$name_html}}){{- end}}{{- range .Methods}}{{$name_html := html .Name}}
-  * [{{node_html $ .Decl false | sanitize}}](#{{$tname_html}}.{{$name_html}}){{- end}}{{- end}}{{- if $.Notes}}{{- range $marker, $item := $.Notes}}
+* [Variables](#pkg-variables){{end}}{{range .Funcs}}
+* [{{node_html $ .Decl false | sanitize | strike_deprecated .Doc}}]({{symbol_link "" .Name}}){{- end}}{{- range .Types}}{{$tname := .Name}}{{$tname_html := html .Name}}
+* [{{printf "type %s" $tname_html | strike_deprecated .Doc}}]({{symbol_link "" $tname}}){{- if is_enum .}}
+  * [Values]({{symbol_link $tname "values"}}){{- end}}{{- range .Funcs}}
+  * [{{node_html $ .Decl false | sanitize | strike_deprecated .Doc}}]({{symbol_link "" .Name}}){{- end}}{{- range .Methods}}
+  * [{{node_html $ .Decl false | sanitize | strike_deprecated .Doc}}]({{symbol_link $tname .Name}}){{- end}}{{- end}}{{- if $.Notes}}{{- range $marker, $item := $.Notes}}
 * [{{noteTitle $marker | html}}s](#pkg-note-{{$marker}}){{end}}{{end}}
 
-{{/* Examples */ -}}
-{{with $.Examples}}#### <a name="pkg-examples">Examples</a>
+{{if show_examples}}{{with $.Examples}}#### <a name="pkg-examples">Examples</a>
 
-{{range .}}* [{{example_name .Name}}](#example_{{.Name}})
+{{range .}}* [{{example_name .Name}}]({{anchor_link (printf "example_%s" .Name)}})
 {{end}}
-{{end}}
-
-{{- /* Package Files */ -}}
-{{with .Filenames}}#### <a name="pkg-files">Package files</a>
+{{end}}{{end}}
+{{- with .Filenames}}#### <a name="pkg-files">Package files</a>
 
 {{range .}}[{{.|filename|html}}]({{.|srcfile_url|html}}) {{end}}
 
-{{end}}
+{{end}}{{end}}{{end}}
 
-{{- /* Constants */}}
-{{- with .Consts}}## <a name="pkg-constants">Constants</a>
+{{- define "consts"}}{{with .PDoc}}{{with .Consts}}## <a name="pkg-constants">Constants</a>
 
 {{range .}}{{node $ .Decl | goCode}}
-{{comment_md .Doc}}{{- end}}{{end}}
+{{platforms $ .}}{{doc_md .Doc}}{{end}}{{end}}{{end}}{{end}}
 
-{{- /* Variables */}}
-{{- with .Vars}}## <a name="pkg-variables">Variables</a>
+{{- define "vars"}}{{with .PDoc}}{{with .Vars}}## <a name="pkg-variables">Variables</a>
 
 {{range .}}{{node $ .Decl | goCode}}
-{{comment_md .Doc}}{{end}}
-{{- end -}}
+{{platforms $ .}}{{doc_md .Doc}}{{end}}{{end}}{{end}}{{end}}
+
+{{- define "funcs"}}{{with .PDoc}}{{range .Funcs}}{{template "func" (symbol $ nil . "##")}}{{end}}{{end}}{{end}}
+
+{{- define "func"}}{{with .Func}}{{$name_html := html .Name}}{{$.Heading}} <a name="{{symbol_anchor "" .Name}}">func</a> [{{$name_html}}]({{get_full_url $.Page .Decl}})
+
+{{node $.Page .Decl | goCode}}
+{{platforms $.Page .}}{{doc_md .Doc}}{{example_md $.Page .Name}}{{callgraph_html $.Page "" .Name}}{{end}}{{end}}
+
+{{- define "types"}}{{with .PDoc}}{{range .Types}}{{template "type" (symbol $ . nil "##")}}{{end}}{{end}}{{end}}
+
+{{- define "type"}}{{with .Type}}{{$tname := .Name}}{{$tname_html := html .Name}}{{$.Heading}} <a name="{{symbol_anchor "" $tname}}">type</a> [{{$tname_html}}]({{get_full_url $.Page .Decl}})
 
-{{- /* Functions */ -}}
-{{- range .Funcs}}{{$name_html := html .Name}}## <a name="{{$name_html}}">func</a> [{{$name_html}}]({{get_full_url $ .Decl}})
+{{node $.Page .Decl | goCode}}
+{{platforms $.Page .}}{{doc_md .Doc -}}
+{{- if is_enum .}}{{$.Heading}}# <a name="{{symbol_anchor $tname "values"}}">Values</a>
 
-{{node $ .Decl | goCode}}
-{{comment_md .Doc -}}
-{{example_html $ .Name -}}
-{{callgraph_html $ "" .Name}}
+{{range .Consts}}{{node $.Page .Decl | goCode }}
+{{platforms $.Page .}}{{doc_md .Doc}}{{- end -}}
+{{- if has_stringer .}}` + "`" + `{{$tname_html}}` + "`" + ` values print by name through [String]({{symbol_link $tname "String"}}).
+
+{{end -}}
+{{- else -}}
+{{- range .Consts}}{{node $.Page .Decl | goCode }}
+{{platforms $.Page .}}{{doc_md .Doc}}{{- end -}}
 {{- end -}}
+{{- range .Vars}}{{node $.Page .Decl | goCode }}
+{{platforms $.Page .}}{{doc_md .Doc}}{{- end -}}
+{{example_md $.Page $tname}}{{implements_html $.Page $tname}}{{methodset_html $.Page $tname}}
+{{- range .Funcs}}{{template "func" (symbol $.Page $.Type . (printf "%s#" $.Heading))}}{{end}}
+{{- range .Methods}}{{template "method" (symbol $.Page $.Type . (printf "%s#" $.Heading))}}{{end}}
+{{- end}}{{end}}
+
+{{- define "method"}}{{with .Func}}{{$name_html := html .Name}}{{$.Heading}} <a name="{{symbol_anchor $.Type.Name .Name}}">func</a> ({{md .Recv}}) [{{$name_html}}]({{get_full_url $.Page .Decl}})
+
+{{node $.Page .Decl | goCode}}
+{{platforms $.Page .}}{{doc_md .Doc}}{{$name := printf "%s_%s" $.Type.Name .Name}}{{example_md $.Page $name}}{{callgraph_html $.Page .Recv .Name}}{{end}}{{end}}
+
+{{- define "groups"}}{{range $group := symbol_groups $}}{{$heading := "##"}}{{with .Title}}{{$heading = "###"}}## <a name="{{$group.Anchor}}">{{md .}}</a>
+
+{{end}}{{range .Funcs}}{{template "func" (symbol $ nil . $heading)}}{{end}}{{range .Types}}{{template "type" (symbol $ . nil $heading)}}{{end}}{{end}}{{end}}
+
+{{- define "subdirs"}}{{with .Dirs}}## <a name="pkg-subdirectories">Subdirectories</a>
 
-{{/* Types */ -}}
-{{range .Types}}{{$tname := .Name}}{{$tname_html := html .Name}}## <a name="{{$tname_html}}">type</a> [{{$tname_html}}]({{get_full_url $ .Decl}})
-
-{{node $ .Decl | goCode}}
-{{comment_md .Doc -}}
-{{- range .Consts}}{{node $ .Decl | goCode }}
-{{comment_md .Doc}}{{- end -}} {{- /* EndConsts */ -}}
-{{- range .Vars}}{{node $ .Decl | goCode }}
-{{comment_md .Doc}}{{- end -}}{{- /* EndVars */ -}}
-{{example_html $ $tname -}}
-{{implements_html $ $tname -}}
-{{methodset_html $ $tname -}}
-
-{{- /* Functions */ -}}
-{{range .Funcs}}{{$name_html := html .Name}}### <a name="{{$name_html}}">func</a> [{{$name_html}}]({{get_full_url $ .Decl}})
-
-{{node $ .Decl | goCode}}
-{{comment_md .Doc -}}
-{{example_html $ .Name}}
-{{- end}}{{/* Functions */ -}}
-{{callgraph_html $ "" .Name}}
-
-{{- /* Methods */ -}}
-{{range .Methods}}{{$name_html := html .Name}}### <a name="{{$tname_html}}.{{$name_html}}">func</a> ({{md .Recv}}) [{{$name_html}}]({{get_full_url $ .Decl}})
-
-{{node $ .Decl | goCode}}
-{{comment_md .Doc -}}
-{{$name := printf "%s_%s" $tname .Name}}{{example_html $ $name -}}
-{{callgraph_html $ .Recv .Name}}
-{{- end}}{{/* Methods */ -}}
-{{- end}}{{/* Types */ -}}
+{{range .List}}{{repeat "  " .Depth}}* [{{.Name}}](./{{.Path}}){{with .Synopsis}} - {{.}}{{end}}
 {{end}}
+{{end}}{{end}}
+
+{{- define "notes"}}{{with .Notes}}{{range $marker, $content := .}}## <a name="pkg-note-{{$marker}}">{{noteTitle $marker | html}}s</a>
+
+{{range .}}* {{with .UID}}**{{md .}}** {{end}}([source]({{note_url $ .}})): {{note_md .}}
+{{end}}
+{{end}}{{end}}{{end}}
+
+{{- define "funcs_page"}}{{with .PDoc}}[{{.Name}}]({{anchor_link "pkg-index"}}) > Functions
+
+# Functions
+
+{{end}}{{template "funcs" $}}{{template "footer" $}}{{end}}
 
-{{- /* Notes */ -}}
-{{with $.Notes}}{{range $marker, $content := .}}## <a name="pkg-note-{{$marker}}">{{noteTitle $marker | html}}s
+{{- define "type_page"}}[{{.Page.PDoc.Name}}]({{anchor_link "pkg-index"}}) > {{.Type.Name}}
 
-<ul style="list-style: none; padding: 0;">{{range .}}
-<li><a href="{{get_full_url $ .}}">&#x261e;</a> {{html .Body}}</li>
-{{end}}</ul>
-{{- end}}{{end}}{{end -}}
+{{template "type" .}}{{template "footer" .Page}}{{end}}
 
-- - -
+{{- define "footer"}}- - -
 Created: {{ current_time | print }}
 Generated by [godoc2md](http://github.com/chriswgerber/godoc2md)
-`
+{{end}}`
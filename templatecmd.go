@@ -0,0 +1,197 @@
+package godoc2md
+
+import (
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/parser"
+	"go/token"
+	"io"
+
+	"golang.org/x/tools/godoc"
+)
+
+// TemplateCmd is the name of the subcommand to inspect templates, as in
+// `godoc2md template dump` and `godoc2md template check <file>`.
+const TemplateCmd = "template"
+
+// samplePkgSource is the package the template check renders templates
+// against. It exercises every part of the built-in templates: constants,
+// variables, functions, enums with a String method, deprecated symbols, notes
+// and examples.
+const samplePkgSource = `// Package sample is a synthetic package used to check templates.
+//
+// Overview
+//
+// It has a heading, a list:
+//
+//   - one
+//   - two
+//
+// and a code block:
+//
+//	sample.New("name")
+package sample
+
+import "fmt"
+
+// Version is the version of the package.
+const Version = "1.0.0"
+
+// Default is the default Thing.
+var Default = New("default")
+
+// Helper does nothing.
+//
+// Deprecated: Use New instead.
+func Helper() {}
+
+// Color is an enum of colors.
+type Color int
+
+// Colors.
+const (
+	Red Color = iota // red
+	Blue             // blue
+)
+
+// String returns the name of c.
+func (c Color) String() string { return [...]string{"Red", "Blue"}[c] }
+
+// Thing is a thing.
+type Thing struct {
+	// Name is the name of the thing.
+	Name string ` + "`json:\"name\"`" + `
+	fmt.Stringer
+}
+
+// BUG(sample): Things are not comparable.
+
+// New returns a new Thing.
+func New(name string) *Thing { return &Thing{Name: name} }
+
+// Do does the thing.
+func (t *Thing) Do() error { return nil }
+`
+
+// sampleTestSource holds the examples of the sample package.
+const sampleTestSource = `package sample_test
+
+import "sample"
+
+func Example() {
+	sample.New("name").Do()
+}
+
+func ExampleThing_Do() {
+	t := sample.New("name")
+	t.Do()
+	// Output:
+}
+`
+
+// TemplateCommand runs the template subcommand named by args[0]:
+//
+//	dump          print the built-in template of the configured format
+//	check <path>  parse the template file, or directory, at path on top of
+//	              the built-in template and render it against a synthetic
+//	              package, reporting any error
+func TemplateCommand(w io.Writer, pres *godoc.Presentation, cfg *Cli, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: %s %s dump | check <path>", cmdName, TemplateCmd)
+	}
+
+	switch args[0] {
+	case "dump":
+		format, err := GetFormat(*cfg.Format)
+		if err != nil {
+			return err
+		}
+		if format.JSON {
+			return fmt.Errorf("format %q has no template", format.Name)
+		}
+		_, err = io.WriteString(w, format.Template)
+		return err
+
+	case "check":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: %s %s check <path>", cmdName, TemplateCmd)
+		}
+		if err := CheckTemplate(pres, cfg, args[1]); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s: ok\n", args[1])
+		return nil
+	}
+
+	return fmt.Errorf("unknown %s subcommand %q, must be one of: check, dump", TemplateCmd, args[0])
+}
+
+// CheckTemplate parses the template file, or directory of template files, at
+// name on top of the built-in template of the configured format and executes
+// it against a synthetic package. Unknown funcs are reported when parsing, and
+// missing fields or wrongly typed arguments when executing.
+func CheckTemplate(pres *godoc.Presentation, cfg *Cli, name string) error {
+	if err := checkModel(cfg, name); err != nil {
+		return err
+	}
+	t, err := NewTemplate(pres, cfg, name)
+	if err != nil {
+		return err
+	}
+
+	info, err := samplePageInfo(pres)
+	if err != nil {
+		return err
+	}
+
+	var data interface{} = info
+	if *cfg.Model {
+		data = NewPackage(pres, cfg, info)
+	}
+
+	if err := t.Execute(io.Discard, data); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+
+	return nil
+}
+
+// samplePageInfo returns the page info of the synthetic sample package, as
+// GetPageInfo would for a package on disk.
+func samplePageInfo(pres *godoc.Presentation) (*godoc.PageInfo, error) {
+	fset := token.NewFileSet()
+
+	file, err := parser.ParseFile(fset, target+"/sample.go", samplePkgSource, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	testFile, err := parser.ParseFile(fset, target+"/sample_test.go", sampleTestSource, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	pdoc, err := doc.NewFromFiles(fset, []*ast.File{file}, "example.com/sample")
+	if err != nil {
+		return nil, err
+	}
+
+	info := &godoc.PageInfo{
+		Dirname:  target,
+		FSet:     fset,
+		PDoc:     pdoc,
+		Examples: doc.Examples(testFile),
+		PAst:     map[string]*ast.File{target + "/sample.go": file},
+	}
+
+	for marker, notes := range pdoc.Notes {
+		if pres.NotesRx == nil || pres.NotesRx.MatchString(marker) {
+			if info.Notes == nil {
+				info.Notes = make(map[string][]*doc.Note)
+			}
+			info.Notes[marker] = notes
+		}
+	}
+
+	return info, nil
+}
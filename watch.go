@@ -0,0 +1,232 @@
+package godoc2md
+
+import (
+	"fmt"
+	"go/build"
+	"io/fs"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/tools/godoc"
+	"golang.org/x/tools/godoc/vfs"
+)
+
+// watchInterval is how often watch mode polls the package sources and the
+// template for changes.
+var watchInterval = 500 * time.Millisecond
+
+// sourceWatch polls the source directories of packages, and the template
+// overrides, for changes.
+type sourceWatch struct {
+	cfg  *Cli
+	args []string
+	// dirs are the source directories of the packages, by argument. Those
+	// read from a module version or a git revision are missing, as they
+	// never change.
+	dirs map[string]string
+	// stamps identify the state of the package directories, by argument,
+	// and of the template, by the empty string.
+	stamps map[string]string
+}
+
+func newSourceWatch(cfg *Cli, args []string) *sourceWatch {
+	sw := &sourceWatch{cfg: cfg, args: args, dirs: map[string]string{}}
+	for _, arg := range args {
+		sw.dirs[arg] = sourceDir(cfg, arg)
+	}
+	sw.stamps = sw.snapshot()
+	return sw
+}
+
+// changes returns the packages whose sources changed since the previous
+// call, and whether the template did.
+func (sw *sourceWatch) changes() (args []string, template bool) {
+	stamps := sw.snapshot()
+	for _, arg := range sw.args {
+		if stamps[arg] != sw.stamps[arg] {
+			args = append(args, arg)
+		}
+	}
+	template = stamps[""] != sw.stamps[""]
+	sw.stamps = stamps
+	return args, template
+}
+
+// snapshot returns the current stamps of the package directories and of the
+// template.
+func (sw *sourceWatch) snapshot() map[string]string {
+	stamps := map[string]string{}
+	for arg, dir := range sw.dirs {
+		if dir != "" {
+			stamps[arg] = dirStamp(dir, false)
+		}
+	}
+	if overrides := *sw.cfg.AltPkgTemplate; overrides != "" {
+		stamps[""] = dirStamp(overrides, true)
+	}
+	return stamps
+}
+
+// reloadTemplate parses the template of pres again, with its overrides.
+func reloadTemplate(pres *godoc.Presentation, cfg *Cli) error {
+	t, err := NewTemplate(pres, cfg, *cfg.AltPkgTemplate)
+	if err != nil {
+		return err
+	}
+	pres.PackageText = t
+	return nil
+}
+
+// watcher rewrites the pages of a site whose sources change.
+type watcher struct {
+	*sourceWatch
+	fs       vfs.NameSpace
+	pres     *godoc.Presentation
+	cfg      *Cli
+	gen      *SiteGenerator
+	filename string
+	pages    []SitePage
+}
+
+// Watch writes the page of every package named in args like WriteSite, and
+// then keeps polling the .go files of their directories, and the template
+// overrides, until interrupted. The pages of the packages whose files change
+// are rewritten, and all of them when the template does. Errors are logged
+// rather than returned, except those of the configuration.
+func Watch(fs vfs.NameSpace, pres *godoc.Presentation, cfg *Cli, args []string) error {
+	if *cfg.OutDir == "" {
+		return fmt.Errorf("watch mode requires -out")
+	}
+	filename, err := pageFilename(cfg)
+	if err != nil {
+		return err
+	}
+	gen, err := siteGenerator(cfg)
+	if err != nil {
+		return err
+	}
+	if err := checkSplit(cfg); err != nil {
+		return err
+	}
+	if err := checkModel(cfg, *cfg.AltPkgTemplate); err != nil {
+		return err
+	}
+
+	w := &watcher{
+		sourceWatch: newSourceWatch(cfg, args),
+		fs:          fs,
+		pres:        pres,
+		cfg:         cfg,
+		gen:         gen,
+		filename:    filename,
+		pages:       make([]SitePage, len(args)),
+	}
+	w.write(args)
+	log.Printf("watching %d packages for changes", len(args))
+
+	for {
+		time.Sleep(watchInterval)
+
+		changed, template := w.changes()
+		if template {
+			if err := reloadTemplate(pres, cfg); err != nil {
+				log.Printf("error parsing template: %v", err)
+			} else {
+				changed = args
+			}
+		}
+		if len(changed) > 0 {
+			w.write(changed)
+		}
+	}
+}
+
+// write rewrites the pages of the packages named by args, and the navigation
+// files of the site generator.
+func (w *watcher) write(args []string) {
+	changed := map[string]bool{}
+	for _, arg := range args {
+		changed[arg] = true
+	}
+
+	for i, arg := range w.args {
+		if !changed[arg] {
+			continue
+		}
+		page, _, site, err := writeSitePage(w.fs, w.pres, w.cfg, w.gen, w.filename, arg, i+1)
+		if err != nil {
+			log.Printf("%s: %v", arg, err)
+			continue
+		}
+		w.pages[i] = site
+		log.Printf("wrote %s", page)
+	}
+
+	if w.gen != nil {
+		var pages []SitePage
+		for _, page := range w.pages {
+			if page.ImportPath != "" {
+				pages = append(pages, page)
+			}
+		}
+		if err := w.gen.writeNav(*w.cfg.OutDir, pages); err != nil {
+			log.Print(err)
+		}
+	}
+}
+
+// dirStamp returns a string identifying the names, sizes and modification
+// times of the .go files of the directory dir, or of all the files below
+// root if recursive. A single file is stamped on its own.
+func dirStamp(root string, recursive bool) string {
+	var stamps []string
+	filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			stamps = append(stamps, p+" "+err.Error())
+			return nil
+		}
+		if d.IsDir() {
+			if p != root && !recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !recursive && !strings.HasSuffix(p, ".go") {
+			return nil
+		}
+		if fi, err := d.Info(); err == nil {
+			stamps = append(stamps, fmt.Sprintf("%s %d %d", p, fi.Size(), fi.ModTime().UnixNano()))
+		}
+		return nil
+	})
+	sort.Strings(stamps)
+	return strings.Join(stamps, "\n")
+}
+
+// sourceDir returns the directory of the working tree the package named by
+// arg is loaded from, or an empty string for packages read from a module
+// version or a git revision, or not found.
+func sourceDir(cfg *Cli, arg string) string {
+	if isVersioned(cfg, arg) || *cfg.Rev != "" {
+		return ""
+	}
+	if cfg.Workspace != nil {
+		if dir, _, ok := cfg.Workspace.Resolve(arg); ok {
+			return dir
+		}
+	}
+	if filepath.IsAbs(arg) || build.IsLocalImport(arg) {
+		dir, err := filepath.Abs(arg)
+		if err != nil {
+			return ""
+		}
+		return dir
+	}
+	if bp, err := build.Import(arg, "", build.FindOnly); err == nil {
+		return bp.Dir
+	}
+	return ""
+}
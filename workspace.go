@@ -0,0 +1,356 @@
+package godoc2md
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Module is one of the Go modules of a workspace.
+type Module struct {
+	// Path is the module path, as declared by its go.mod.
+	Path string
+	// Dir is the directory of the module.
+	Dir string
+	// RepoDir is the directory of the module relative to the root of its
+	// repository, in slash form. It is empty for a module at the root.
+	RepoDir string
+}
+
+// majorVersionRx matches the major version suffix of a module path, as in
+// "example.com/repo/v2".
+var majorVersionRx = regexp.MustCompile(`/v([2-9]|[1-9][0-9]+)$`)
+
+// repoPrefix returns the import path of the repository root, the module path
+// without the directory of the module in the repository. The major version
+// suffix of a module developed on a major branch, rather than in a major
+// subdirectory, is dropped too. Modules whose path doesn't end with their
+// directory are treated as the repository root.
+func (m Module) repoPrefix() string {
+	unversioned := majorVersionRx.ReplaceAllString(m.Path, "")
+	if m.RepoDir == "" {
+		return unversioned
+	}
+	for _, p := range []string{m.Path, unversioned} {
+		if prefix := strings.TrimSuffix(p, "/"+m.RepoDir); prefix != p {
+			return prefix
+		}
+	}
+	return unversioned
+}
+
+// Workspace is a go.work workspace and the modules it uses, or the main
+// module alone outside of workspaces.
+type Workspace struct {
+	// Root is the root directory of the repository of the modules.
+	Root    string
+	Modules []Module
+}
+
+// findWorkFile returns the go.work file of the workspace dir belongs to, or
+// an empty string. Like the go command, it honors the GOWORK environment
+// variable, including GOWORK=off.
+func findWorkFile(dir string) string {
+	switch gowork := os.Getenv("GOWORK"); gowork {
+	case "off":
+		return ""
+	case "":
+	default:
+		return gowork
+	}
+
+	return findUp(dir, "go.work", false)
+}
+
+// ParseWorkFile returns the module directories named by the `use` directives
+// of the go.work file content data, as written.
+func ParseWorkFile(data []byte) ([]string, error) {
+	return directiveArgs(data, "use")
+}
+
+// directiveArgs returns the unquoted arguments of the verb directives of the
+// go.mod or go.work file content data, either given one per directive or in a
+// parenthesized block. Comments are ignored.
+func directiveArgs(data []byte, verb string) ([]string, error) {
+	var args []string
+	inBlock := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		fields, err := directiveFields(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+
+		switch {
+		case len(fields) == 0:
+			continue
+		case inBlock && fields[0] == ")":
+			inBlock = false
+			continue
+		case inBlock:
+		case fields[0] == verb && len(fields) == 2 && fields[1] == "(":
+			inBlock = true
+			continue
+		case fields[0] == verb && len(fields) == 2:
+			fields = fields[1:]
+		case fields[0] == verb:
+			return nil, fmt.Errorf("line %d: invalid %s directive", n, verb)
+		default:
+			continue
+		}
+
+		arg, err := unquotePath(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+		args = append(args, arg)
+	}
+
+	return args, scanner.Err()
+}
+
+// directiveFields splits a line of a go.mod or go.work file into its fields,
+// up to its comment. Quoted fields are kept whole, quotes included, even if
+// they contain spaces or slashes.
+func directiveFields(line string) ([]string, error) {
+	var fields []string
+	for {
+		line = strings.TrimLeft(line, " \t")
+		if line == "" || strings.HasPrefix(line, "//") {
+			return fields, nil
+		}
+
+		end := -1
+		switch line[0] {
+		case '"':
+			for i := 1; i < len(line); i++ {
+				if line[i] == '\\' {
+					i++ // skip the escaped character
+				} else if line[i] == '"' {
+					end = i + 1
+					break
+				}
+			}
+		case '`':
+			if i := strings.IndexByte(line[1:], '`'); i >= 0 {
+				end = i + 2
+			}
+		default:
+			if end = strings.IndexAny(line, " \t"); end < 0 {
+				end = len(line)
+			}
+		}
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated quoted string %s", line)
+		}
+
+		fields = append(fields, line[:end])
+		line = line[end:]
+	}
+}
+
+// unquotePath returns the path p of a go.mod or go.work directive, which may
+// be quoted.
+func unquotePath(p string) (string, error) {
+	if strings.HasPrefix(p, `"`) || strings.HasPrefix(p, "`") {
+		return strconv.Unquote(p)
+	}
+	return p, nil
+}
+
+// LoadWorkspace reads the go.work file filename, and the go.mod file of every
+// module it uses.
+func LoadWorkspace(filename string) (*Workspace, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	dirs, err := ParseWorkFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+
+	workDir, err := filepath.Abs(filepath.Dir(filename))
+	if err != nil {
+		return nil, err
+	}
+	w := &Workspace{Root: findRepoRoot(workDir)}
+	for _, dir := range dirs {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(workDir, filepath.FromSlash(dir))
+		}
+		m, err := w.addModule(filepath.Join(dir, "go.mod"))
+		if err != nil {
+			return nil, err
+		}
+		w.Modules = append(w.Modules, m)
+	}
+
+	return w, nil
+}
+
+// LoadModule reads the go.mod file filename of a module used outside of a
+// workspace.
+func LoadModule(filename string) (*Workspace, error) {
+	dir, err := filepath.Abs(filepath.Dir(filename))
+	if err != nil {
+		return nil, err
+	}
+	w := &Workspace{Root: findRepoRoot(dir)}
+	m, err := w.addModule(filename)
+	if err != nil {
+		return nil, err
+	}
+	w.Modules = append(w.Modules, m)
+
+	return w, nil
+}
+
+// addModule reads the go.mod file filename, and returns its module, located
+// in the repository of the workspace.
+func (w *Workspace) addModule(filename string) (Module, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return Module{}, err
+	}
+	modPath, err := ParseModulePath(data)
+	if err != nil {
+		return Module{}, fmt.Errorf("%s: %v", filename, err)
+	}
+
+	dir, err := filepath.Abs(filepath.Dir(filename))
+	if err != nil {
+		return Module{}, err
+	}
+	repoDir := ""
+	if rel, err := filepath.Rel(w.Root, dir); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+		repoDir = filepath.ToSlash(rel)
+	}
+
+	return Module{Path: modPath, Dir: dir, RepoDir: repoDir}, nil
+}
+
+// ParseModulePath returns the module path declared by the go.mod file content
+// data. Comments are ignored, and the path may be quoted, or given in a
+// parenthesized block.
+func ParseModulePath(data []byte) (string, error) {
+	paths, err := directiveArgs(data, "module")
+	if err != nil {
+		return "", err
+	}
+	if len(paths) == 0 {
+		return "", fmt.Errorf("no module directive")
+	}
+	return paths[0], nil
+}
+
+// findModFile returns the go.mod file of the module dir belongs to, or an
+// empty string.
+func findModFile(dir string) string {
+	return findUp(dir, "go.mod", false)
+}
+
+// findRepoRoot returns the root directory of the repository dir belongs to,
+// the closest one with a .git entry, or dir itself if there is none.
+func findRepoRoot(dir string) string {
+	if git := findUp(dir, ".git", true); git != "" {
+		return filepath.Dir(git)
+	}
+	return dir
+}
+
+// findUp returns the path of the closest entry called name in dir or one of
+// its parents, or an empty string. Directories match only if anyKind is set.
+func findUp(dir, name string, anyKind bool) string {
+	for {
+		filename := filepath.Join(dir, name)
+		if fi, err := os.Stat(filename); err == nil && (anyKind || !fi.IsDir()) {
+			return filename
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// ModuleOf returns the module providing the package at importPath, or nil.
+func (w *Workspace) ModuleOf(importPath string) *Module {
+	var found *Module
+	for i, m := range w.Modules {
+		if importPath == m.Path || strings.HasPrefix(importPath, m.Path+"/") {
+			if found == nil || len(m.Path) > len(found.Path) {
+				found = &w.Modules[i]
+			}
+		}
+	}
+	return found
+}
+
+// moduleAt returns the module containing the directory dir, or nil.
+func (w *Workspace) moduleAt(dir string) *Module {
+	var found *Module
+	for i, m := range w.Modules {
+		rel, err := filepath.Rel(m.Dir, dir)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if found == nil || len(m.Dir) > len(found.Dir) {
+			found = &w.Modules[i]
+		}
+	}
+	return found
+}
+
+// Resolve maps the package argument arg, either an import path or a local
+// directory, to the directory and import path of a package of one of the
+// modules of the workspace. It reports false for packages outside of them.
+func (w *Workspace) Resolve(arg string) (dir, importPath string, ok bool) {
+	if filepath.IsAbs(arg) || strings.HasPrefix(arg, ".") {
+		abs, err := filepath.Abs(arg)
+		if err != nil {
+			return "", "", false
+		}
+		m := w.moduleAt(abs)
+		if m == nil {
+			return "", "", false
+		}
+		rel, _ := filepath.Rel(m.Dir, abs)
+		return abs, path.Join(m.Path, filepath.ToSlash(rel)), true
+	}
+
+	m := w.ModuleOf(arg)
+	if m == nil {
+		return "", "", false
+	}
+	rel := strings.TrimPrefix(strings.TrimPrefix(arg, m.Path), "/")
+	return filepath.Join(m.Dir, filepath.FromSlash(rel)), arg, true
+}
+
+// repoPrefix returns the import path of the repository root of the
+// workspace, as the longest common prefix of the repository prefixes of its
+// modules.
+func (w *Workspace) repoPrefix() string {
+	if len(w.Modules) == 0 {
+		return ""
+	}
+
+	prefix := strings.Split(w.Modules[0].repoPrefix(), "/")
+	for _, m := range w.Modules[1:] {
+		elems := strings.Split(m.repoPrefix(), "/")
+		n := 0
+		for n < len(prefix) && n < len(elems) && prefix[n] == elems[n] {
+			n++
+		}
+		prefix = prefix[:n]
+	}
+	return strings.Join(prefix, "/")
+}
@@ -0,0 +1,97 @@
+package godoc2md
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseModulePath(t *testing.T) {
+	for _, tt := range []struct {
+		name, data, want string
+	}{
+		{"plain", "module example.com/m\n\ngo 1.20\n", "example.com/m"},
+		{"comments", "// The module.\nmodule example.com/m // trailing\n", "example.com/m"},
+		{"quoted", "module \"example.com/m\"\n", "example.com/m"},
+		{"raw quoted", "module `example.com/m`\n", "example.com/m"},
+		{"block", "module (\n\t// The module.\n\texample.com/m\n)\n", "example.com/m"},
+		{"major version", "module example.com/m/v2\n", "example.com/m/v2"},
+		{"commented out", "// module example.com/old\nmodule example.com/m\n", "example.com/m"},
+	} {
+		got, err := ParseModulePath([]byte(tt.data))
+		if err != nil || got != tt.want {
+			t.Errorf("%s: ParseModulePath = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+
+	for _, data := range []string{
+		"",
+		"go 1.20\n",
+		"module\n",
+		"module example.com/m extra\n",
+		"module \"example.com/m\n",
+	} {
+		if got, err := ParseModulePath([]byte(data)); err == nil {
+			t.Errorf("ParseModulePath(%q) = %q, want an error", data, got)
+		}
+	}
+}
+
+func TestParseWorkFile(t *testing.T) {
+	for _, tt := range []struct {
+		name, data string
+		want       []string
+	}{
+		{"single", "go 1.20\n\nuse ./a\n", []string{"./a"}},
+		{"directives", "use ./a\nuse ./b // trailing\n", []string{"./a", "./b"}},
+		{"block", "use (\n\t./a\n\t./b\n)\n", []string{"./a", "./b"}},
+		{
+			"block comments",
+			"use ( // modules\n\t./a // first\n\t// ./skipped\n\t\"./b c\" // quoted\n) // end\n",
+			[]string{"./a", "./b c"},
+		},
+		{"quoted", "use \"./a\"\nuse `./b`\n", []string{"./a", "./b"}},
+		{"quoted slashes", "use \"./a//b\" // comment\n", []string{"./a//b"}},
+		{"escaped quote", "use \"./a\\\"b\"\n", []string{`./a"b`}},
+		{"replace ignored", "use ./a\nreplace example.com/x => ./x\n", []string{"./a"}},
+		{"none", "go 1.20\n", nil},
+	} {
+		got, err := ParseWorkFile([]byte(tt.data))
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: ParseWorkFile = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+
+	for _, data := range []string{
+		"use ./a ./b\n",
+		"use\n",
+		"use (\n\t\"./a\n)\n",
+		"use `./a\n",
+		"use \"./a\\\"\n",
+	} {
+		if got, err := ParseWorkFile([]byte(data)); err == nil {
+			t.Errorf("ParseWorkFile(%q) = %q, want an error", data, got)
+		}
+	}
+}
+
+func TestRepoPrefix(t *testing.T) {
+	for _, tt := range []struct {
+		path, repoDir, want string
+	}{
+		{"example.com/repo", "", "example.com/repo"},
+		{"example.com/repo/v2", "", "example.com/repo"},
+		{"example.com/repo/v10", "", "example.com/repo"},
+		{"example.com/repo/sub", "sub", "example.com/repo"},
+		{"example.com/repo/sub/v3", "sub", "example.com/repo"},
+		{"example.com/repo/v2", "v2", "example.com/repo"},
+		{"example.com/repo/sub/v2", "sub/v2", "example.com/repo"},
+		{"example.com/other", "sub", "example.com/other"},
+		{"example.com/other/v2", "sub", "example.com/other"},
+		{"example.com/repo/v1", "", "example.com/repo/v1"},
+	} {
+		m := Module{Path: tt.path, RepoDir: tt.repoDir}
+		if got := m.repoPrefix(); got != tt.want {
+			t.Errorf("Module{%s, %s}.repoPrefix() = %q, want %q", tt.path, tt.repoDir, got, tt.want)
+		}
+	}
+}